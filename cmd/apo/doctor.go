@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/config"
+)
+
+// patExpiryWarningDays is how many days ahead of pat_expires apo doctor
+// warns.
+const patExpiryWarningDays = 14
+
+// doctorProbe checks one REST area apo uses.
+type doctorProbe struct {
+	name  string
+	scope string // PAT scope the area needs
+	run   func(ctx context.Context, svc api.Service) (string, error)
+}
+
+var doctorProbes = []doctorProbe{
+	{"Projects", "Project and Team (Read)", func(ctx context.Context, svc api.Service) (string, error) {
+		projects, err := svc.ListProjects(ctx)
+		return fmt.Sprintf("%d visible", len(projects)), err
+	}},
+	{"Work items", "Work Items (Read)", func(ctx context.Context, svc api.Service) (string, error) {
+		items, err := svc.GetMyWorkItems(ctx)
+		return fmt.Sprintf("%d assigned to you", len(items)), err
+	}},
+	{"Build", "Build (Read)", func(ctx context.Context, svc api.Service) (string, error) {
+		builds, err := svc.ListBuilds(ctx, "", "", 1)
+		if err != nil {
+			return "", err
+		}
+		pipelines, err := svc.ListPipelines(ctx)
+		return fmt.Sprintf("%d pipelines, %d recent build(s)", len(pipelines), len(builds)), err
+	}},
+	{"Code", "Code (Read)", func(ctx context.Context, svc api.Service) (string, error) {
+		repos, err := svc.ListRepositories(ctx)
+		if err != nil {
+			return "", err
+		}
+		prs, err := svc.GetActivePullRequests(ctx, 0)
+		return fmt.Sprintf("%d repositories, %d active PR(s)", len(repos), len(prs)), err
+	}},
+}
+
+// runDoctor checks the configuration, connectivity, authentication and
+// each REST area apo uses, and reports what to fix. It exits non-zero when
+// a check fails.
+func runDoctor() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Printf("🩺 apo doctor (profile: %s)\n", cfg.ProfileName())
+	fmt.Println(strings.Repeat("─", 40))
+
+	failed := false
+	report := func(ok bool, name, detail string) {
+		mark := "✅"
+		if !ok {
+			mark = "❌"
+			failed = true
+		}
+		fmt.Printf("%s %-13s %s\n", mark, name, detail)
+	}
+	warn := func(name, detail string) {
+		fmt.Printf("⚠️  %-13s %s\n", name, detail)
+	}
+
+	if err := cfg.ValidateWithProject(); err != nil {
+		report(false, "Config", err.Error()+" — run 'apo config'")
+		os.Exit(1)
+	}
+	auth := cfg.Authentication()
+	method := "PAT"
+	switch {
+	case auth.Mode == config.AuthBearer:
+		method = "bearer token"
+	case cfg.PAT == "" && cfg.PATCommand != "":
+		method = "PAT from pat_command"
+	}
+	report(true, "Config", fmt.Sprintf("%s at %s (%s)", cfg.Project, cfg.OrganizationURL(), method))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	uncached := *cfg
+	uncached.DisableCache = true
+	client := newClient(&uncached)
+
+	start := time.Now()
+	conn, err := client.ConnectionData(ctx)
+	switch {
+	case api.IsUnreachable(err):
+		report(false, "Connectivity", api.Explain(err))
+		os.Exit(1)
+	case err != nil && !api.IsUnauthorized(err):
+		report(false, "Connectivity", api.Explain(err))
+		os.Exit(1)
+	}
+	report(true, "Connectivity", fmt.Sprintf("reached %s in %s", cfg.OrganizationURL(), time.Since(start).Round(time.Millisecond)))
+
+	if err != nil || conn.AuthenticatedUser.IsAnonymous() {
+		report(false, "Identity", "not authenticated — the "+method+" is invalid, expired or revoked")
+		os.Exit(1)
+	}
+	user := conn.AuthenticatedUser
+	identity := user.ProviderDisplayName
+	if account := user.Account(); account != "" {
+		identity += " <" + account + ">"
+	}
+	report(true, "Identity", identity)
+
+	var missing []string
+	for _, probe := range doctorProbes {
+		detail, err := probe.run(ctx, client)
+		switch {
+		case err == nil:
+			report(true, probe.name, detail)
+		case api.IsUnauthorized(err) || api.IsForbidden(err):
+			// The identity is valid, so a rejection here means the
+			// credential is not allowed into this area.
+			report(false, probe.name, fmt.Sprintf("%s — missing the %q scope?", api.Summary(err), probe.scope))
+			missing = append(missing, probe.scope)
+		default:
+			report(false, probe.name, api.Explain(err))
+		}
+	}
+
+	if rl, ok := client.RateLimit(); ok {
+		warn("Rate limit", fmt.Sprintf("%d of %d remaining", rl.Remaining, rl.Limit))
+	}
+
+	if auth.Mode == config.AuthPAT {
+		checkPATExpiry(cfg, report, warn)
+	}
+
+	if len(missing) > 0 {
+		fmt.Printf("\nMissing scopes: %s\n", strings.Join(missing, ", "))
+		fmt.Println("Edit the PAT under User settings → Personal access tokens, or create a new one.")
+	}
+	fmt.Println()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// checkPATExpiry reports on pat_expires, which Azure DevOps does not expose
+// to the PAT itself.
+func checkPATExpiry(cfg *config.Config, report func(bool, string, string), warn func(string, string)) {
+	if cfg.PATExpires == "" {
+		warn("PAT expiry", "unknown — run 'apo config' and enter the PAT's expiry date to be warned before it expires")
+		return
+	}
+	expires, err := time.ParseInLocation(time.DateOnly, cfg.PATExpires, time.Local)
+	if err != nil {
+		warn("PAT expiry", fmt.Sprintf("cannot parse pat_expires %q; use YYYY-MM-DD", cfg.PATExpires))
+		return
+	}
+	y, m, d := time.Now().Date()
+	days := int(expires.Sub(time.Date(y, m, d, 0, 0, 0, 0, time.Local)).Hours() / 24)
+	switch {
+	case days < 0:
+		report(false, "PAT expiry", fmt.Sprintf("expired on %s — create a new PAT and run 'apo config'", cfg.PATExpires))
+	case days == 0:
+		warn("PAT expiry", "expires today")
+	case days <= patExpiryWarningDays:
+		warn("PAT expiry", fmt.Sprintf("expires in %d day(s), on %s", days, cfg.PATExpires))
+	default:
+		report(true, "PAT expiry", "valid until "+cfg.PATExpires)
+	}
+}
@@ -3,76 +3,255 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/user/apo/internal/agent"
 	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/api/cassette"
 	"github.com/user/apo/internal/config"
+	"github.com/user/apo/internal/mockserver"
+	"github.com/user/apo/internal/secret"
 	"github.com/user/apo/internal/ui"
+	"github.com/user/apo/internal/ui/terminal"
 )
 
 const version = "0.3.0"
 
+// profile is the connection profile selected with --profile.
+var profile string
+
 func main() {
-	if len(os.Args) < 2 {
+	config.PromptPassphrase = promptPassphrase
+	args := parseGlobalFlags(os.Args[1:])
+	if len(args) < 1 {
 		runTUI()
 		return
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "ui", "tui":
 		runTUI()
 	case "config":
-		runConfig()
+		runConfig(args[1:])
+	case "doctor":
+		runDoctor()
+	case "wiql":
+		runWIQL(args[1:])
+	case "wi":
+		runWorkItem(args[1:])
+	case "mock-server":
+		runMockServer(args[1:])
 	case "ask":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			fmt.Fprintln(os.Stderr, "Usage: apo ask <question>")
 			os.Exit(1)
 		}
-		runAsk(strings.Join(os.Args[2:], " "))
+		runAsk(strings.Join(args[1:], " "))
 	case "help", "-h", "--help":
 		printHelp()
 	case "version", "-v", "--version":
 		fmt.Printf("apo v%s - Azure Prod Ops CLI\n", version)
 	default:
-		runAsk(strings.Join(os.Args[1:], " "))
+		runAsk(strings.Join(args, " "))
 	}
 }
 
+// parseGlobalFlags removes --profile from the front of args and returns the
+// rest.
+func parseGlobalFlags(args []string) []string {
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--profile" || args[0] == "-p":
+			if len(args) < 2 {
+				fmt.Fprintln(os.Stderr, "Usage: apo --profile <name> [command]")
+				os.Exit(1)
+			}
+			profile, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--profile="):
+			profile, args = strings.TrimPrefix(args[0], "--profile="), args[1:]
+		default:
+			return args
+		}
+	}
+	return args
+}
+
+// promptPassphrase asks for the passphrase that encrypts PATs.
+func promptPassphrase() (string, error) {
+	fmt.Fprint(os.Stderr, "Passphrase for stored PATs: ")
+	pass, err := terminal.ReadPassword()
+	fmt.Fprintln(os.Stderr)
+	return pass, err
+}
+
+// loadConfig loads the configuration for the selected profile.
+func loadConfig() (*config.Config, error) {
+	return config.LoadProfile(profile)
+}
+
 func runTUI() {
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	app, err := ui.NewApp(cfg)
+	app, err := ui.NewApp(cfg, newClient(cfg))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintln(os.Stderr, "Run 'apo config' to configure your connection.")
 		os.Exit(1)
 	}
+	app.SetClientFactory(func(cfg *config.Config) api.Service {
+		return newClient(cfg)
+	})
 
-	if err := app.Run(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.Run(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func runConfig() {
-	cfg, _ := config.Load()
+// newClient creates an API client. When APO_CASSETTE is set, traffic is
+// recorded to or replayed from that file according to APO_CASSETTE_MODE,
+// with the response cache disabled so every request reaches the cassette.
+func newClient(cfg *config.Config) *api.Client {
+	path := os.Getenv("APO_CASSETTE")
+	if path == "" {
+		return api.NewClient(cfg)
+	}
+
+	uncached := *cfg
+	uncached.DisableCache = true
+	client := api.NewClient(&uncached)
+	mode, err := cassette.ParseMode(os.Getenv("APO_CASSETTE_MODE"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	rec, err := cassette.New(path, mode, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	rec.AddSecret(cfg.PAT)
+	client.SetTransport(rec)
+	return client
+}
+
+func runConfig(args []string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		configureConnection(cfg)
+		return
+	}
+
+	name := ""
+	if len(args) > 1 {
+		name = args[1]
+	}
+	switch args[0] {
+	case "list", "ls":
+		for _, n := range cfg.ProfileNames() {
+			p, _ := cfg.Profile(n)
+			marker := " "
+			if n == cfg.ProfileName() {
+				marker = "*"
+			}
+			fmt.Printf("%s %-16s %s/%s\n", marker, n, p.Organization, p.Project)
+		}
+		return
+	case "add":
+		if name == "" {
+			break
+		}
+		if err := cfg.AddProfile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.UseProfile(name)
+		configureConnection(cfg)
+		return
+	case "remove", "rm":
+		if name == "" {
+			break
+		}
+		if err := cfg.RemoveProfile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := cfg.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed profile %s\n", name)
+		return
+	case "switch", "use":
+		if name == "" {
+			break
+		}
+		if err := cfg.SetActiveProfile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := cfg.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Switched to profile %s\n", name)
+		return
+	case "encrypt":
+		if name == "" {
+			name = secret.KeyFile
+		}
+		encryptPATs(cfg, name)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Usage: apo config [list | add <name> | remove <name> | switch <name> | encrypt [keyfile|passphrase|off]]")
+	os.Exit(1)
+}
+
+// configureConnection prompts for the connection of the profile in use,
+// saves it and tests it.
+func configureConnection(cfg *config.Config) {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	fmt.Println()
-	fmt.Println("🔧 Azure DevOps Configuration")
+	fmt.Printf("🔧 Azure DevOps Configuration (profile: %s)\n", cfg.ProfileName())
 	fmt.Println(strings.Repeat("─", 40))
 
-	fmt.Printf("Organization [%s]: ", cfg.Organization)
-	scanner.Scan()
-	if input := strings.TrimSpace(scanner.Text()); input != "" {
-		cfg.Organization = input
+	if cfg.IsOnPrem() {
+		fmt.Printf("Server URL [%s]: ", cfg.APIURL)
+		scanner.Scan()
+		if input := strings.TrimSpace(scanner.Text()); input != "" {
+			cfg.APIURL = input
+		}
+		fmt.Printf("Collection [%s]: ", cfg.CollectionName())
+		scanner.Scan()
+		if input := strings.TrimSpace(scanner.Text()); input != "" {
+			cfg.Collection = input
+		}
+	} else {
+		fmt.Printf("Organization [%s]: ", cfg.Organization)
+		scanner.Scan()
+		if input := strings.TrimSpace(scanner.Text()); input != "" {
+			cfg.Organization = input
+		}
 	}
 
 	fmt.Printf("Project [%s]: ", cfg.Project)
@@ -81,10 +260,28 @@ func runConfig() {
 		cfg.Project = input
 	}
 
-	fmt.Print("Personal Access Token (PAT): ")
-	scanner.Scan()
-	if input := strings.TrimSpace(scanner.Text()); input != "" {
-		cfg.PAT = input
+	if cfg.Authentication().Mode == config.AuthBearer {
+		fmt.Println("Authentication: bearer tokens (auth in the config file)")
+	} else {
+		if cfg.PATCommand != "" {
+			fmt.Printf("Personal Access Token (PAT) [from pat_command %q]: ", cfg.PATCommand)
+		} else {
+			fmt.Print("Personal Access Token (PAT): ")
+		}
+		scanner.Scan()
+		if input := strings.TrimSpace(scanner.Text()); input != "" {
+			cfg.PAT = input
+			cfg.PATExpires = "" // the date belonged to the old PAT
+		}
+		cfg.PATExpires = promptPATExpiry(scanner, cfg.PATExpires)
+	}
+
+	if cfg.PATStore == "" && cfg.PAT != "" {
+		fmt.Print("Encrypt the PAT with a key stored in ~/.config/apo/key? [Y/n]: ")
+		scanner.Scan()
+		if input := strings.ToLower(strings.TrimSpace(scanner.Text())); input == "" || input == "y" || input == "yes" {
+			cfg.PATStore = secret.KeyFile
+		}
 	}
 
 	if err := cfg.Save(); err != nil {
@@ -95,18 +292,153 @@ func runConfig() {
 	fmt.Printf("\n✅ Configuration saved to %s\n", config.GetConfigPath())
 
 	fmt.Print("\nTesting connection... ")
-	client := api.NewClient(cfg)
-	projects, err := client.ListProjects()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := newClient(cfg)
+	projects, err := client.ListProjects(ctx)
 	if err != nil {
-		fmt.Printf("❌\n   %v\n", err)
+		fmt.Printf("❌\n   %s\n", api.Explain(err))
 		return
 	}
 	fmt.Printf("✅ Connected! Found %d project(s).\n", len(projects))
 	fmt.Println("\nRun 'apo' to launch the TUI!")
 }
 
+// promptPATExpiry asks when the PAT expires, which Azure DevOps shows only
+// when the PAT is created, so apo doctor can warn ahead of it. An empty
+// answer keeps the current date and "-" clears it.
+func promptPATExpiry(scanner *bufio.Scanner, current string) string {
+	for {
+		if current != "" {
+			fmt.Printf("PAT expiry date (YYYY-MM-DD, - for none) [%s]: ", current)
+		} else {
+			fmt.Print("PAT expiry date (YYYY-MM-DD, optional): ")
+		}
+		if !scanner.Scan() {
+			return current
+		}
+		input := strings.TrimSpace(scanner.Text())
+		switch input {
+		case "":
+			return current
+		case "-":
+			return ""
+		}
+		if _, err := time.Parse(time.DateOnly, input); err == nil {
+			return input
+		}
+		fmt.Println("   Use the form 2026-12-31.")
+	}
+}
+
+// encryptPATs re-saves every profile's PAT with the given store: "keyfile",
+// "passphrase", or "off" for plaintext. It also migrates plaintext configs.
+func encryptPATs(cfg *config.Config, store string) {
+	switch store {
+	case secret.KeyFile:
+	case secret.Passphrase:
+		if os.Getenv("APO_PASSPHRASE") == "" {
+			pass, err := promptPassphrase()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+			again, _ := terminal.ReadPassword()
+			fmt.Fprintln(os.Stderr)
+			if pass == "" || pass != again {
+				fmt.Fprintln(os.Stderr, "Error: passphrases are empty or do not match")
+				os.Exit(1)
+			}
+			cfg.SetPassphrase(pass)
+		}
+	case "off", "none", "plaintext":
+		store = ""
+	default:
+		fmt.Fprintln(os.Stderr, "Usage: apo config encrypt [keyfile|passphrase|off]")
+		os.Exit(1)
+	}
+
+	cfg.PATStore = store
+	if err := cfg.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+	switch store {
+	case "":
+		fmt.Println("PATs are now stored in plaintext")
+	case secret.KeyFile:
+		fmt.Printf("PATs are now encrypted with %s\n", config.GetKeyPath())
+	default:
+		fmt.Println("PATs are now encrypted with your passphrase (or APO_PASSPHRASE)")
+	}
+}
+
+func runMockServer(args []string) {
+	fs := flag.NewFlagSet("mock-server", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8089", "listen address")
+	org := fs.String("org", "demo", "organization to serve")
+	pat := fs.String("pat", "mock", "PAT required by Basic auth (empty disables auth)")
+	clientID := fs.String("client-id", "", "client accepted by the fake token endpoint (enables bearer auth)")
+	clientSecret := fs.String("client-secret", "mock-secret", "secret required with --client-id")
+	tokenTTL := fs.Duration("token-ttl", time.Hour, "lifetime of issued bearer tokens")
+	latency := fs.Duration("latency", 0, "delay added to every response")
+	throttle := fs.Int("throttle-every", 0, "answer every Nth request with 429")
+	pageSize := fs.Int("page-size", 0, "max items per page")
+	maxVersion := fs.String("max-api-version", "", "reject newer api-versions, like Azure DevOps Server (e.g. 6.0)")
+	deny := fs.String("deny", "", "comma-separated areas (work,build,code,project) to answer as if the PAT lacked their scope")
+	fixtures := fs.String("fixtures", "", "directory of fixture JSON overriding the built-in set")
+	export := fs.String("export-fixtures", "", "write the built-in fixtures to this directory and exit")
+	fs.Parse(args)
+
+	if *export != "" {
+		if err := mockserver.ExportFixtures(*export); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Fixtures written to %s\n", *export)
+		return
+	}
+
+	srv, err := mockserver.New(mockserver.Options{
+		Organization:  *org,
+		PAT:           *pat,
+		ClientID:      *clientID,
+		ClientSecret:  *clientSecret,
+		TokenTTL:      *tokenTTL,
+		Latency:       *latency,
+		ThrottleEvery: *throttle,
+		PageSize:      *pageSize,
+		MaxAPIVersion: *maxVersion,
+		DenyAreas:     strings.FieldsFunc(*deny, func(r rune) bool { return r == ',' }),
+		FixturesDir:   *fixtures,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("🧪 Mock Azure DevOps listening on http://%s\n\n", *addr)
+	fmt.Println("Point apo at it with:")
+	fmt.Printf("  export AZURE_DEVOPS_URL=http://%s\n", *addr)
+	fmt.Printf("  export AZURE_DEVOPS_ORG=%s\n", *org)
+	fmt.Println("  export AZURE_DEVOPS_PROJECT=demo")
+	fmt.Printf("  export AZURE_DEVOPS_PAT=%s\n", *pat)
+	if *clientID != "" {
+		fmt.Println("\nor, for bearer auth, set in the config:")
+		fmt.Printf(`  "auth": {"mode": "bearer", "token_url": "http://%s/mock/oauth2/v2.0/token",`+"\n", *addr)
+		fmt.Printf(`           "client_id": %q, "client_secret": %q}`+"\n", *clientID, *clientSecret)
+	}
+
+	if err := http.ListenAndServe(*addr, srv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func runAsk(query string) {
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -118,9 +450,12 @@ func runAsk(query string) {
 		os.Exit(1)
 	}
 
-	client := api.NewClient(cfg)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := newClient(cfg)
 	ag := agent.New(client)
-	result := ag.Ask(query)
+	result := ag.Ask(ctx, query)
 
 	if !result.Success {
 		fmt.Printf("❌ %s\n", result.Message)
@@ -154,6 +489,21 @@ Usage:
   apo ask <question>    Ask a natural language question
   apo <question>        Ask a natural language question (shortcut)
   apo config            Configure Azure DevOps connection
+  apo config list       List connection profiles
+  apo config add|remove|switch <name>
+                        Add, remove or activate a connection profile
+  apo config encrypt [keyfile|passphrase|off]
+                        Encrypt stored PATs (or store them in plaintext)
+  apo --profile <name> [command]
+                        Use a connection profile for one command
+  apo doctor            Check the connection, identity, PAT scopes and expiry
+  apo wiql "<WIQL>"     Run a WIQL query and list the matching work items
+  apo wiql -q <path|id> Run a saved query (e.g. "Shared Queries/Active bugs")
+  apo wiql -l           List saved queries
+  apo wi create --type <type> --title <title> [--assign @me] [--parent <id>]
+                        Create a work item (also --description, --area,
+                        --iteration, --priority)
+  apo mock-server       Serve a fake Azure DevOps API for demos/tests
   apo help              Show this help
   apo version           Show version
 
@@ -163,9 +513,15 @@ TUI Navigation:
   [↑↓/jk]     Navigate items
   [g/G]       Go to top/bottom
   [Enter]     Open detail view
+  [e]         Work item detail: edit a field ([s] state, [a] assign, [t] title)
   [f]         Filter current list
   [Tab]       Cycle tabs
-  [r]         Refresh data
+  [r]         Refresh data (cached resources within their TTL)
+  [R]         Force refresh, bypassing the cache
+  [s]         Boards: run a saved query (or back to my work items)
+  [o]         Toggle org-wide mode (work items, builds, PRs across projects)
+  [p]         Switch project (remembered per organization)
+  [P]         Switch connection profile
   [Esc]       Back / Cancel
   [q]         Quit
 
@@ -178,8 +534,21 @@ Configuration:
   Config file: ~/.config/apo/config.json
 
   Environment variables (override config file):
+    AZURE_DEVOPS_PROFILE  Connection profile to use
     AZURE_DEVOPS_ORG
     AZURE_DEVOPS_PROJECT  
     AZURE_DEVOPS_PAT
+    AZURE_DEVOPS_URL
+    AZURE_DEVOPS_SERVER_TYPE
+                          cloud (default), or server for Azure DevOps Server
+    AZURE_DEVOPS_COLLECTION
+                          Project collection on Azure DevOps Server
+    APO_NO_CACHE          Disable the on-disk response cache
+    APO_PASSPHRASE        Passphrase for PATs encrypted with a passphrase
+    AZURE_DEVOPS_CLIENT_SECRET
+                          Client secret for bearer auth
+
+  Record / replay HTTP traffic (PAT and identities scrubbed):
+    APO_CASSETTE=file.json APO_CASSETTE_MODE=record|replay
 `, version)
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/terminal"
+)
+
+// runWIQL runs an ad-hoc WIQL query or a saved query and prints the
+// matching work items, or lists the saved queries.
+func runWIQL(args []string) {
+	fs := flag.NewFlagSet("wiql", flag.ExitOnError)
+	saved := fs.String("q", "", "run the saved query with this path (e.g. \"Shared Queries/Active bugs\") or ID")
+	list := fs.Bool("l", false, "list saved queries")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: apo wiql \"<WIQL>\" | apo wiql -q <path|id> | apo wiql -l")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	query := strings.Join(fs.Args(), " ")
+	if !*list && *saved == "" && query == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.ValidateWithProject(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'apo config' to configure your connection.")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	client := newClient(cfg)
+
+	if *list {
+		queries, err := client.ListQueries(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s\n", api.Explain(err))
+			os.Exit(1)
+		}
+		for _, q := range queries {
+			fmt.Printf("%-6s %s\n", q.QueryType, q.Path)
+		}
+		return
+	}
+
+	var items []domain.WorkItem
+	if *saved != "" {
+		items, err = client.RunQuery(ctx, *saved)
+	} else {
+		items, err = client.QueryWorkItems(ctx, query)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", api.Explain(err))
+		os.Exit(1)
+	}
+	for _, item := range items {
+		fmt.Printf("%-7d %-11s %-10s %-50s %s\n", item.ID,
+			terminal.Truncate(item.Type(), 11),
+			terminal.Truncate(item.State(), 10),
+			terminal.Truncate(item.Title(), 50),
+			item.AssignedTo())
+	}
+	fmt.Printf("\n%d work item(s)\n", len(items))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/config"
+	"github.com/user/apo/internal/domain"
+)
+
+// runWorkItem dispatches the "apo wi" subcommands.
+func runWorkItem(args []string) {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, "Usage: apo wi create --type <type> --title <title> [options]")
+		os.Exit(1)
+	}
+	runCreateWorkItem(args[1:])
+}
+
+// runCreateWorkItem creates a work item, checking it against the process
+// template first so a missing required field is reported up front.
+func runCreateWorkItem(args []string) {
+	fs := flag.NewFlagSet("wi create", flag.ExitOnError)
+	typ := fs.String("type", "", "work item type, e.g. Bug, Task or \"User Story\" (required)")
+	title := fs.String("title", "", "title (required)")
+	description := fs.String("description", "", "description (HTML allowed)")
+	assign := fs.String("assign", "", "assignee's name or email, or @me")
+	area := fs.String("area", "", "area path (default: the project's root area)")
+	iteration := fs.String("iteration", "", "iteration path (default: the project's root iteration)")
+	priority := fs.Int("priority", 0, "priority, 1 (highest) to 4")
+	parent := fs.Int("parent", 0, "ID of the parent work item")
+	fs.Parse(args)
+
+	if *typ == "" || strings.TrimSpace(*title) == "" {
+		fmt.Fprintln(os.Stderr, "Usage: apo wi create --type <type> --title <title> [options]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if *priority < 0 || *priority > 4 {
+		fmt.Fprintln(os.Stderr, "Error: --priority must be between 1 and 4")
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.ValidateWithProject(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'apo config' to configure your connection.")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	client := newClient(cfg)
+
+	fields := map[string]interface{}{"System.Title": strings.TrimSpace(*title)}
+	set := func(name, value string) {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	set("System.Description", *description)
+	set("System.AreaPath", *area)
+	set("System.IterationPath", *iteration)
+	if *priority != 0 {
+		fields["Microsoft.VSTS.Common.Priority"] = *priority
+	}
+	if *assign != "" {
+		assignee, err := api.ResolveMe(ctx, client, *assign)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Could not look up your identity: %s\n", api.Explain(err))
+			os.Exit(1)
+		}
+		fields["System.AssignedTo"] = assignee
+	}
+
+	wit, err := findWorkItemType(ctx, client, *typ, fields)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+		os.Exit(1)
+	}
+
+	item, err := client.CreateWorkItem(ctx, wit.Name, fields, *parent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", api.Explain(err))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Created %s #%d: %s\n", wit.Name, item.ID, item.Title())
+	if *parent != 0 {
+		fmt.Printf("   Child of #%d\n", *parent)
+	}
+	fmt.Printf("   %s\n", workItemURL(cfg, item))
+}
+
+// findWorkItemType looks up a work item type by name, ignoring case, and
+// checks that fields has everything it requires.
+func findWorkItemType(ctx context.Context, svc api.Service, name string, fields map[string]interface{}) (domain.WorkItemType, error) {
+	types, err := svc.WorkItemTypes(ctx)
+	if err != nil {
+		return domain.WorkItemType{}, fmt.Errorf("%s", api.Explain(err))
+	}
+	var names []string
+	for _, t := range types {
+		if strings.EqualFold(t.Name, name) {
+			if missing := t.MissingFields(fields); len(missing) > 0 {
+				return t, fmt.Errorf("%s requires %s, which apo cannot set", t.Name, strings.Join(missing, ", "))
+			}
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+	return domain.WorkItemType{}, fmt.Errorf("unknown work item type %q; this project has %s", name, strings.Join(names, ", "))
+}
+
+// workItemURL returns the web URL of a work item.
+func workItemURL(cfg *config.Config, item domain.WorkItem) string {
+	project := cfg.Project
+	if p := item.TeamProject(); p != "" {
+		project = p
+	}
+	return fmt.Sprintf("%s/%s/_workitems/edit/%d", cfg.OrganizationURL(), url.PathEscape(project), item.ID)
+}
@@ -2,6 +2,8 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -41,12 +43,12 @@ type pattern struct {
 
 // Agent interprets natural language queries.
 type Agent struct {
-	client   *api.Client
+	client   api.Service
 	patterns []pattern
 }
 
-// New creates a new Agent.
-func New(client *api.Client) *Agent {
+// New creates a new Agent backed by client.
+func New(client api.Service) *Agent {
 	a := &Agent{client: client}
 	a.initPatterns()
 	return a
@@ -106,14 +108,14 @@ func compile(exprs ...string) []*regexp.Regexp {
 }
 
 // Ask interprets a query and returns a result.
-func (a *Agent) Ask(query string) *Result {
+func (a *Agent) Ask(ctx context.Context, query string) *Result {
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return &Result{Success: true, Message: "Please ask me something!", Suggestions: []string{"Try: 'help'"}}
 	}
 
 	intent := a.matchIntent(query)
-	return a.execute(intent)
+	return a.execute(ctx, intent)
 }
 
 func (a *Agent) matchIntent(query string) Intent {
@@ -127,7 +129,7 @@ func (a *Agent) matchIntent(query string) Intent {
 	return IntentUnknown
 }
 
-func (a *Agent) execute(intent Intent) *Result {
+func (a *Agent) execute(ctx context.Context, intent Intent) *Result {
 	switch intent {
 	case IntentHelp:
 		return &Result{
@@ -141,74 +143,85 @@ func (a *Agent) execute(intent Intent) *Result {
 			},
 		}
 	case IntentMyWorkItems:
-		items, err := a.client.GetMyWorkItems()
+		items, err := a.client.GetMyWorkItems(ctx)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		if len(items) == 0 {
 			return &Result{Success: true, Message: "No work items assigned to you.", Data: items}
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d work item(s):", len(items)), Data: items}
 	case IntentFailedBuilds:
-		builds, err := a.client.GetFailedBuilds(15)
+		builds, err := a.client.GetFailedBuilds(ctx, 15)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		if len(builds) == 0 {
 			return &Result{Success: true, Message: "No failed builds! 🎉", Data: builds}
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d failed build(s):", len(builds)), Data: builds}
 	case IntentRunningBuilds:
-		builds, err := a.client.GetRunningBuilds()
+		builds, err := a.client.GetRunningBuilds(ctx)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		if len(builds) == 0 {
 			return &Result{Success: true, Message: "No builds currently running.", Data: builds}
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d running build(s):", len(builds)), Data: builds}
 	case IntentRecentBuilds:
-		builds, err := a.client.ListBuilds("", "", 15)
+		builds, err := a.client.ListBuilds(ctx, "", "", 15)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d recent build(s):", len(builds)), Data: builds}
 	case IntentListPipelines:
-		pipelines, err := a.client.ListPipelines()
+		pipelines, err := a.client.ListPipelines(ctx)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d pipeline(s):", len(pipelines)), Data: pipelines}
 	case IntentListRepos:
-		repos, err := a.client.ListRepositories()
+		repos, err := a.client.ListRepositories(ctx)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d repository(ies):", len(repos)), Data: repos}
 	case IntentActivePRs:
-		prs, err := a.client.GetActivePullRequests(20)
+		prs, err := a.client.GetActivePullRequests(ctx, 20)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		if len(prs) == 0 {
 			return &Result{Success: true, Message: "No active pull requests.", Data: prs}
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d active PR(s):", len(prs)), Data: prs}
 	case IntentListProjects:
-		projects, err := a.client.ListProjects()
+		projects, err := a.client.ListProjects(ctx)
 		if err != nil {
-			return &Result{Success: false, Message: fmt.Sprintf("Error: %v", err)}
+			return errorResult(err)
 		}
 		return &Result{Success: true, Message: fmt.Sprintf("Found %d project(s):", len(projects)), Data: projects}
 	default:
 		return &Result{
-			Success: true,
-			Message: "I'm not sure what you're asking.",
+			Success:     true,
+			Message:     "I'm not sure what you're asking.",
 			Suggestions: []string{"Try: 'help' to see what I can do"},
 		}
 	}
 }
 
+func errorResult(err error) *Result {
+	if errors.Is(err, context.Canceled) {
+		return &Result{Success: false, Message: "Canceled."}
+	}
+	result := &Result{Success: false, Message: "Error: " + api.Explain(err)}
+	if api.IsUnauthorized(err) || api.IsForbidden(err) {
+		result.Suggestions = []string{"Run 'apo config' to update your connection"}
+	}
+	return result
+}
+
 // FormatResult formats result data for display.
 func FormatResult(data interface{}) string {
 	var sb strings.Builder
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// projectWorkers bounds how many projects AcrossProjects queries at once.
+const projectWorkers = 4
+
+// AcrossProjects calls fetch with a Service for each project, at most
+// projectWorkers at a time, and concatenates the results in project order.
+// A project that fails does not stop the others: its results are left out
+// and its error, prefixed with the project name, is joined into the
+// returned error.
+func AcrossProjects[T any](ctx context.Context, svc Service, projects []string, fetch func(context.Context, Service) ([]T, error)) ([]T, error) {
+	results := make([][]T, len(projects))
+	errs := make([]error, len(projects))
+
+	sem := make(chan struct{}, projectWorkers)
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			items, err := fetch(ctx, svc.ForProject(project))
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", project, err)
+				return
+			}
+			results[i] = items
+		}()
+	}
+	wg.Wait()
+
+	var all []T
+	for _, items := range results {
+		all = append(all, items...)
+	}
+	if err := ctx.Err(); err != nil {
+		return all, err
+	}
+	return all, errors.Join(errs...)
+}
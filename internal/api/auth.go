@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/apo/internal/config"
+)
+
+// Authenticator supplies the credentials sent with each request.
+type Authenticator interface {
+	// Authorization returns the value of the Authorization header.
+	Authorization(ctx context.Context) (string, error)
+	// Reject reports that the server refused a header returned by
+	// Authorization. It returns true if a fresh credential may be
+	// available, in which case the request is tried once more.
+	Reject(header string) bool
+}
+
+// expirySkew is how long before a token expires it is refreshed, so it does
+// not lapse in flight.
+const expirySkew = time.Minute
+
+// NewAuthenticator returns the Authenticator selected by cfg.Auth.
+func NewAuthenticator(cfg *config.Config) Authenticator {
+	auth := cfg.Authentication()
+	if auth.Mode != config.AuthBearer {
+		return NewPATAuth(cfg.PAT, cfg.PATCommand)
+	}
+	if auth.TokenCommand != "" {
+		return &BearerAuth{tokens: &tokenCache{fetch: commandToken(auth.TokenCommand)}}
+	}
+	return NewBearerAuth(auth.TokenURL, auth.ClientID, auth.ClientSecret, auth.Scope)
+}
+
+// PATAuth authenticates with a personal access token in Basic auth: the
+// token from the config or, when that is empty, one printed by pat_command.
+// A command token is kept for the life of the process and fetched again
+// after the server rejects it.
+type PATAuth struct {
+	tokens *tokenCache
+}
+
+// NewPATAuth returns a PATAuth using pat, or command when pat is empty.
+func NewPATAuth(pat, command string) *PATAuth {
+	tokens := &tokenCache{token: pat}
+	if pat == "" && command != "" {
+		tokens.fetch = commandToken(command)
+	}
+	return &PATAuth{tokens: tokens}
+}
+
+// Authorization implements Authenticator.
+func (a *PATAuth) Authorization(ctx context.Context) (string, error) {
+	token, err := a.tokens.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(":"+token)), nil
+}
+
+// Reject implements Authenticator.
+func (a *PATAuth) Reject(header string) bool {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+	if err != nil {
+		return false
+	}
+	return a.tokens.invalidate(strings.TrimPrefix(string(raw), ":"))
+}
+
+// BearerAuth authenticates with OAuth bearer tokens, such as Entra ID
+// access tokens, obtained with the client credentials grant or printed by a
+// command. Tokens are refreshed shortly before they expire and after the
+// server rejects one.
+type BearerAuth struct {
+	tokens *tokenCache
+}
+
+// NewBearerAuth returns a BearerAuth requesting tokens from tokenURL with
+// the client credentials grant.
+func NewBearerAuth(tokenURL, clientID, clientSecret, scope string) *BearerAuth {
+	client := &http.Client{Timeout: config.DefaultTimeout}
+	fetch := func(ctx context.Context) (string, time.Time, error) {
+		return requestToken(ctx, client, tokenURL, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {clientID},
+			"client_secret": {clientSecret},
+			"scope":         {scope},
+		})
+	}
+	return &BearerAuth{tokens: &tokenCache{fetch: fetch}}
+}
+
+// Authorization implements Authenticator.
+func (a *BearerAuth) Authorization(ctx context.Context) (string, error) {
+	token, err := a.tokens.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// Reject implements Authenticator.
+func (a *BearerAuth) Reject(header string) bool {
+	return a.tokens.invalidate(strings.TrimPrefix(header, "Bearer "))
+}
+
+// tokenCache holds a token and fetches a new one when it is missing, about
+// to expire or was rejected. Without fetch the token is fixed. Only one
+// fetch runs at a time; requests needing a token meanwhile wait for its
+// result rather than for the lock, so a slow token endpoint does not hold
+// up requests that already have a valid token.
+type tokenCache struct {
+	fetch func(ctx context.Context) (token string, expires time.Time, err error)
+
+	mu       sync.Mutex
+	token    string
+	expires  time.Time   // zero when the token does not expire
+	fetching *tokenFetch // nil when no fetch is in flight
+}
+
+// tokenFetch is a fetch in flight. done is closed once token and err are
+// set.
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func (t *tokenCache) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	if t.fetch == nil || (t.token != "" && (t.expires.IsZero() || time.Until(t.expires) > expirySkew)) {
+		defer t.mu.Unlock()
+		return t.token, nil
+	}
+	if f := t.fetching; f != nil {
+		t.mu.Unlock()
+		select {
+		case <-f.done:
+			if f.err != nil && ctx.Err() == nil && (errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded)) {
+				return t.get(ctx) // the request that fetched gave up; fetch again
+			}
+			return f.token, f.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	f := &tokenFetch{done: make(chan struct{})}
+	t.fetching = f
+	t.mu.Unlock()
+
+	token, expires, err := t.fetch(ctx)
+	t.mu.Lock()
+	if err == nil {
+		t.token, t.expires = token, expires
+	}
+	t.fetching = nil
+	t.mu.Unlock()
+	f.token, f.err = token, err
+	close(f.done)
+	return token, err
+}
+
+// invalidate drops a rejected token and reports whether a new one can be
+// fetched. A token already replaced by a concurrent request is kept.
+func (t *tokenCache) invalidate(rejected string) bool {
+	if t.fetch == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token == rejected {
+		t.token = ""
+	}
+	return true
+}
+
+// commandToken returns a fetch func that runs command in the shell and
+// uses its trimmed stdout as a token that never expires.
+func commandToken(command string) func(context.Context) (string, time.Time, error) {
+	return func(ctx context.Context) (string, time.Time, error) {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", command)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", time.Time{}, fmt.Errorf("token command failed: %w: %s", err, msg)
+			}
+			return "", time.Time{}, fmt.Errorf("token command failed: %w", err)
+		}
+		token := strings.TrimSpace(stdout.String())
+		if token == "" {
+			return "", time.Time{}, fmt.Errorf("token command printed no token")
+		}
+		return token, time.Time{}, nil
+	}
+}
+
+// requestToken posts an OAuth 2.0 token request and returns the access
+// token and when it expires.
+func requestToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values) (string, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("reading token response: %w", err)
+	}
+
+	var payload struct {
+		AccessToken      string      `json:"access_token"`
+		ExpiresIn        json.Number `json:"expires_in"` // a string from Entra ID v1 endpoints and some proxies
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", time.Time{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || payload.AccessToken == "" {
+		msg := payload.ErrorDescription
+		if msg == "" {
+			msg = payload.Error
+		}
+		return "", time.Time{}, fmt.Errorf("token request failed (status %d): %s", resp.StatusCode, msg)
+	}
+
+	var expires time.Time
+	if seconds, err := payload.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		expires = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return payload.AccessToken, expires, nil
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/user/apo/internal/config"
+)
+
+// cacheTTLs are how long a cached response is served without contacting
+// the server, keyed by API path suffix. Resources not listed are always
+// revalidated, using If-None-Match when the server sent an ETag.
+var cacheTTLs = map[string]time.Duration{
+	"_apis/projects":          24 * time.Hour,
+	"_apis/pipelines":         time.Hour,
+	"_apis/git/repositories":  time.Hour,
+	"_apis/wit/workitemtypes": 24 * time.Hour,
+}
+
+// The cache is pruned when a transport is created and every pruneEvery
+// stores after that: entries older than cacheMaxAge are removed, then the
+// oldest ones beyond cacheMaxEntries.
+const (
+	cacheMaxAge     = 7 * 24 * time.Hour
+	cacheMaxEntries = 2000
+	pruneEvery      = 200
+)
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context whose requests skip fresh cache entries
+// and always go to the server. Stored ETags are still sent, so unchanged
+// resources cost a 304 rather than a full download.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// cacheHeader is set on responses served from the cache: "hit" for fresh
+// entries and "stale" for entries served because the server could not be
+// reached.
+const cacheHeader = "X-Apo-Cache"
+
+type stalenessKey struct{}
+
+// Staleness records whether requests made with a context were answered
+// from the cache because Azure DevOps could not be reached, so the caller
+// can show the data as offline rather than live.
+type Staleness struct {
+	mu  sync.Mutex
+	err error
+}
+
+// WithStaleness returns a context whose stale cache fallbacks are recorded
+// in the returned Staleness.
+func WithStaleness(ctx context.Context) (context.Context, *Staleness) {
+	s := &Staleness{}
+	return context.WithValue(ctx, stalenessKey{}, s), s
+}
+
+// Err returns the network error of the first request answered from the
+// cache instead of the server, or nil if there was none. IsUnreachable
+// reports true for it.
+func (s *Staleness) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Staleness) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// cacheEntry is a stored response.
+type cacheEntry struct {
+	Key      string              `json:"key"` // scope and URL
+	ETag     string              `json:"etag,omitempty"`
+	StoredAt time.Time           `json:"stored_at"`
+	Header   map[string][]string `json:"header,omitempty"`
+	Body     []byte              `json:"body"`
+}
+
+// cachedHeaders are the response headers kept with a cache entry.
+var cachedHeaders = []string{"Content-Type", "ETag", continuationHeader}
+
+// cacheTransport is an http.RoundTripper that caches successful GET
+// responses on disk. It serves entries within their TTL directly, makes
+// conditional requests for the rest, and falls back to stale entries when
+// the server cannot be reached. Entries are keyed by scope and URL, so
+// connections with different credentials never share them.
+type cacheTransport struct {
+	dir    string
+	scope  string
+	next   http.RoundTripper
+	now    func() time.Time
+	stores atomic.Int64
+}
+
+func newCacheTransport(dir, scope string, next http.RoundTripper) *cacheTransport {
+	t := &cacheTransport{dir: dir, scope: scope, next: next, now: time.Now}
+	t.prune()
+	return t
+}
+
+// cacheScope identifies whose responses a cache entry holds: the profile
+// and a fingerprint of its credential, which is not stored itself.
+func cacheScope(cfg *config.Config) string {
+	auth := cfg.Authentication()
+	credential := auth.Mode + "\x00" + cfg.PAT + "\x00" + cfg.PATCommand
+	if auth.Mode == config.AuthBearer {
+		credential = auth.Mode + "\x00" + auth.TokenURL + "\x00" + auth.ClientID + "\x00" + auth.TokenCommand
+	}
+	sum := sha256.Sum256([]byte(credential))
+	return cfg.ProfileName() + "/" + hex.EncodeToString(sum[:8])
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet {
+		return next.RoundTrip(req)
+	}
+
+	key := t.scope + " " + req.URL.String()
+	entry := t.load(key)
+
+	if entry != nil && !cacheBypassed(req.Context()) {
+		if ttl := ttlFor(req.URL.Path); ttl > 0 && t.now().Sub(entry.StoredAt) < ttl {
+			return entry.response(req), nil
+		}
+	}
+
+	if entry != nil && entry.ETag != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		if entry != nil && req.Context().Err() == nil {
+			if s, ok := req.Context().Value(stalenessKey{}).(*Staleness); ok {
+				s.record(&url.Error{Op: req.Method, URL: req.URL.String(), Err: err})
+			}
+			resp := entry.response(req)
+			resp.Header.Set(cacheHeader, "stale")
+			return resp, nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		entry.StoredAt = t.now()
+		t.store(key, entry)
+		return entry.response(req), nil
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		entry := &cacheEntry{Key: key, ETag: resp.Header.Get("ETag"), StoredAt: t.now(), Body: body}
+		entry.Header = make(map[string][]string)
+		for _, name := range cachedHeaders {
+			if values := resp.Header.Values(name); len(values) > 0 {
+				entry.Header[name] = values
+			}
+		}
+		t.store(key, entry)
+	}
+	return resp, nil
+}
+
+func ttlFor(path string) time.Duration {
+	for suffix, ttl := range cacheTTLs {
+		if strings.HasSuffix(path, suffix) {
+			return ttl
+		}
+	}
+	return 0
+}
+
+func (t *cacheTransport) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *cacheTransport) load(key string) *cacheEntry {
+	data, err := os.ReadFile(t.path(key))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil || entry.Key != key {
+		return nil
+	}
+	return &entry
+}
+
+// store writes an entry atomically. Failures only cost a future cache miss,
+// so they are ignored.
+func (t *cacheTransport) store(key string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(t.dir, "entry-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if tmp.Close() == nil {
+		os.Rename(tmp.Name(), t.path(key))
+	}
+	if t.stores.Add(1)%pruneEvery == 0 {
+		t.prune()
+	}
+}
+
+// prune removes entries not stored or revalidated within cacheMaxAge, then
+// the least recently stored ones beyond cacheMaxEntries. Like store, it
+// ignores failures.
+func (t *cacheTransport) prune() {
+	dirEntries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	var kept []file
+	cutoff := t.now().Add(-cacheMaxAge)
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(t.dir, de.Name())
+		if info.ModTime().Before(cutoff) {
+			os.Remove(path) // including temp files left by a crash
+			continue
+		}
+		if strings.HasSuffix(de.Name(), ".json") {
+			kept = append(kept, file{path, info.ModTime()})
+		}
+	}
+	if len(kept) <= cacheMaxEntries {
+		return
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].modTime.After(kept[j].modTime) })
+	for _, f := range kept[cacheMaxEntries:] {
+		os.Remove(f.path)
+	}
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := make(http.Header)
+	for name, values := range e.Header {
+		for _, v := range values {
+			header.Add(name, v)
+		}
+	}
+	header.Set(cacheHeader, "hit")
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
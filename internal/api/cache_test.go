@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/user/apo/internal/config"
+)
+
+func TestCacheScope(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"count":1,"value":[{"name":"for %s"}]}`, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	get := func(scope, auth string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/demo/_apis/projects", nil)
+		req.Header.Set("Authorization", auth)
+		resp, err := newCacheTransport(dir, scope, nil).RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body [128]byte
+		n, _ := resp.Body.Read(body[:])
+		return string(body[:n])
+	}
+
+	first := get("work/a", "alice")
+	if again := get("work/a", "alice"); again != first || requests != 1 {
+		t.Errorf("second request in the same scope: %q after %d requests, want the cached %q", again, requests, first)
+	}
+	if other := get("home/b", "bob"); other == first || requests != 2 {
+		t.Errorf("request in another scope: %q after %d requests, want a fresh response", other, requests)
+	}
+
+	pat := func(token string) string { return cacheScope(&config.Config{PAT: token}) }
+	if pat("one") == pat("two") {
+		t.Error("two PATs share a cache scope")
+	}
+	if pat("one") != pat("one") {
+		t.Error("the same PAT gets different cache scopes")
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, age time.Duration) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	expired := write("expired.json", cacheMaxAge+time.Hour)
+	leftover := write("entry-123", cacheMaxAge+time.Hour)
+	recent := write("recent.json", time.Minute)
+	newCacheTransport(dir, "", nil)
+	for path, want := range map[string]bool{expired: false, leftover: false, recent: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s kept = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+
+	// Beyond the entry cap the least recently stored go first.
+	var oldest []string
+	for i := 0; i < cacheMaxEntries+5; i++ {
+		path := write(fmt.Sprintf("e%04d.json", i), time.Duration(i)*time.Second)
+		if i >= cacheMaxEntries-1 { // 2006 files, counting recent.json
+			oldest = append(oldest, path)
+		}
+	}
+	newCacheTransport(dir, "", nil)
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != cacheMaxEntries {
+		t.Errorf("%d entries after pruning, want %d", len(entries), cacheMaxEntries)
+	}
+	for _, path := range oldest {
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("%s was kept, want it pruned as one of the oldest", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,480 @@
+// Package cassette records Azure DevOps HTTP traffic to disk and replays it.
+//
+// A Recorder is an http.RoundTripper. In record mode it forwards requests to
+// the real transport and appends each exchange to a JSON cassette file, with
+// credentials and identities scrubbed. In replay mode it answers requests
+// from the cassette without touching the network:
+//
+//	rec, err := cassette.New("testdata/failed-builds.json", cassette.Replay, nil)
+//	client := api.NewClient(cfg)
+//	client.SetTransport(rec)
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	Replay Mode = iota
+	Record
+)
+
+// ParseMode converts "record" or "replay" to a Mode.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "replay":
+		return Replay, nil
+	case "record":
+		return Record, nil
+	}
+	return Replay, fmt.Errorf("unknown cassette mode %q (want record or replay)", s)
+}
+
+// Interaction is one recorded request/response pair.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an HTTP request. Headers are not
+// recorded, so credentials never reach the cassette.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   string              `json:"body"`
+}
+
+// Cassette is the on-disk format.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// recordedHeaders are the response headers kept in a cassette. Everything
+// else, such as cookies and X-VSS-UserData, is dropped.
+var recordedHeaders = []string{
+	"Content-Type",
+	"Retry-After",
+	"X-Ms-Continuationtoken",
+	"X-Ratelimit-Resource",
+	"X-Ratelimit-Limit",
+	"X-Ratelimit-Remaining",
+	"X-Ratelimit-Reset",
+	"X-Ratelimit-Delay",
+	"Etag",
+	"Activityid",
+}
+
+// Recorder records or replays HTTP exchanges. It is safe for concurrent use.
+type Recorder struct {
+	mode     Mode
+	path     string
+	next     http.RoundTripper
+	scrubber *Scrubber
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New creates a Recorder for the cassette at path. In Replay mode the file
+// must exist. In Record mode requests are sent through next (or
+// http.DefaultTransport when nil) and any existing file is overwritten.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{mode: mode, path: path, next: next, scrubber: NewScrubber()}
+
+	if mode == Replay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("parsing cassette: %w", err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// AddSecret registers a value, such as a PAT, that must never be written
+// to the cassette.
+func (r *Recorder) AddSecret(secret string) {
+	r.scrubber.AddSecret(secret)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.mode == Replay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := normalizeURL(req.URL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request.Method != req.Method || normalizeURLString(in.Request.URL) != key {
+			continue
+		}
+		if in.Request.Body != "" && in.Request.Body != string(body) {
+			continue
+		}
+		r.used[i] = true
+		return toHTTPResponse(req, in.Response), nil
+	}
+	return nil, fmt.Errorf("cassette: no recorded interaction for %s %s", req.Method, key)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := make(map[string][]string)
+	for _, name := range recordedHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			header[name] = values
+		}
+	}
+
+	in := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.scrubber.String(req.URL.String()),
+			Body:   r.scrubber.String(string(body)),
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: header,
+			Body:   r.scrubber.Body(respBody),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// save writes the cassette; it must be called with r.mu held. Saving after
+// every interaction keeps the file complete even if apo exits abruptly.
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("creating cassette directory: %w", err)
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
+
+func toHTTPResponse(req *http.Request, rec Response) *http.Response {
+	header := make(http.Header)
+	for name, values := range rec.Header {
+		for _, v := range values {
+			header.Add(name, v)
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}
+}
+
+func normalizeURL(u *url.URL) string {
+	c := *u
+	c.RawQuery = c.Query().Encode() // sorts parameters
+	c.User = nil
+	return c.String()
+}
+
+func normalizeURLString(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return normalizeURL(u)
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	guidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// minReplacedID and minReplacedName are the shortest identity IDs and
+// names replaced outside identity objects.
+const (
+	minReplacedID   = 16
+	minReplacedName = 4
+)
+
+// identityIDKeys are the members of an identity object that hold tenant
+// identifiers: the identity's GUID and its descriptors.
+var identityIDKeys = []string{"id", "localId", "originId", "entityId", "descriptor", "subjectDescriptor"}
+
+// identityNameKeys are the members of an identity object that hold a
+// person's name.
+var identityNameKeys = []string{"displayName", "providerDisplayName", "customDisplayName", "uniqueName"}
+
+// Scrubber replaces secrets and identities with stable placeholders, so the
+// same person maps to the same fake user, with the same fake IDs, across a
+// cassette. Identity names and IDs are also replaced where they appear in
+// other strings, such as comments, URLs and @mention markup, once they have
+// been seen in an identity object of the same or an earlier body.
+type Scrubber struct {
+	mu      sync.Mutex
+	secrets []string
+	names   map[string]string
+	emails  map[string]string
+	ids     map[string]string
+}
+
+// NewScrubber returns an empty scrubber.
+func NewScrubber() *Scrubber {
+	return &Scrubber{names: make(map[string]string), emails: make(map[string]string), ids: make(map[string]string)}
+}
+
+// AddSecret registers a value to redact wherever it appears.
+func (s *Scrubber) AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets = append(s.secrets, secret)
+}
+
+// String redacts secrets, email addresses and the names and IDs of
+// identities already seen in s.
+func (s *Scrubber) String(str string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scrubString(str)
+}
+
+// Body scrubs a response body. JSON bodies have identity objects rewritten
+// field by field; anything else is scrubbed as plain text.
+func (s *Scrubber) Body(body []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return s.scrubString(string(body))
+	}
+	// Learn every identity in the body before rewriting any string, so a
+	// text field naming a person is scrubbed even when it comes before the
+	// person's identity object.
+	s.collect(v)
+	data, err := json.Marshal(s.scrubValue(v))
+	if err != nil {
+		return s.scrubString(string(body))
+	}
+	return string(data)
+}
+
+// isIdentity reports whether a JSON object describes a person or group.
+func isIdentity(obj map[string]interface{}) bool {
+	return obj["uniqueName"] != nil || obj["descriptor"] != nil || obj["subjectDescriptor"] != nil || obj["providerDisplayName"] != nil
+}
+
+// collect assigns fakes to the names and IDs of every identity object in v,
+// visiting object members in sorted order so the numbering is stable.
+func (s *Scrubber) collect(v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if isIdentity(val) {
+			for _, k := range identityIDKeys {
+				if id, ok := val[k].(string); ok && id != "" {
+					s.fakeID(id)
+				}
+			}
+			for _, k := range identityNameKeys {
+				if name, ok := val[k].(string); ok && name != "" && !emailPattern.MatchString(name) {
+					s.fakeName(name)
+				}
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			s.collect(val[k])
+		}
+	case []interface{}:
+		for _, child := range val {
+			s.collect(child)
+		}
+	}
+}
+
+// scrubValue rewrites v in place using the fakes collect assigned.
+func (s *Scrubber) scrubValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		isIdentity := isIdentity(val)
+		if isIdentity {
+			// Map the IDs first, so the identity's own URLs use the fakes.
+			for _, k := range identityIDKeys {
+				if id, ok := val[k].(string); ok && id != "" {
+					val[k] = s.fakeID(id)
+				}
+			}
+		}
+		for k, child := range val {
+			if isIdentity {
+				switch k {
+				case "displayName", "providerDisplayName", "customDisplayName":
+					if name, ok := child.(string); ok {
+						val[k] = s.fakeName(name)
+						continue
+					}
+				case "uniqueName":
+					if name, ok := child.(string); ok && !emailPattern.MatchString(name) {
+						val[k] = s.fakeName(name)
+						continue
+					}
+				case "imageUrl", "_links":
+					delete(val, k)
+					continue
+				}
+				if slices.Contains(identityIDKeys, k) {
+					continue
+				}
+			}
+			val[k] = s.scrubValue(child)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = s.scrubValue(child)
+		}
+		return val
+	case string:
+		return s.scrubString(val)
+	}
+	return v
+}
+
+func (s *Scrubber) scrubString(str string) string {
+	for _, secret := range s.secrets {
+		str = strings.ReplaceAll(str, secret, "REDACTED")
+	}
+	// Short IDs and names would also match unrelated text.
+	str = replaceKnown(str, s.ids, minReplacedID)
+	str = replaceKnown(str, s.names, minReplacedName)
+	return emailPattern.ReplaceAllStringFunc(str, s.fakeEmail)
+}
+
+// replaceKnown replaces every key of fakes at least minLen long with its
+// value, longest first so that "Jane Roe" is not half-replaced as "Jane".
+func replaceKnown(str string, fakes map[string]string, minLen int) string {
+	reals := make([]string, 0, len(fakes))
+	for real := range fakes {
+		if len(real) >= minLen && strings.Contains(str, real) {
+			reals = append(reals, real)
+		}
+	}
+	slices.SortFunc(reals, func(a, b string) int { return len(b) - len(a) })
+	for _, real := range reals {
+		str = strings.ReplaceAll(str, real, fakes[real])
+	}
+	return str
+}
+
+func (s *Scrubber) fakeName(name string) string {
+	if fake, ok := s.names[name]; ok {
+		return fake
+	}
+	fake := fmt.Sprintf("User %d", len(s.names)+1)
+	s.names[name] = fake
+	return fake
+}
+
+// fakeID maps an identity ID to a fake of the same kind: a GUID to a GUID,
+// a descriptor such as "aad.MjE3..." to one with the same prefix.
+func (s *Scrubber) fakeID(id string) string {
+	if fake, ok := s.ids[id]; ok {
+		return fake
+	}
+	var fake string
+	if guidPattern.MatchString(id) {
+		fake = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(s.ids)+1)
+	} else {
+		prefix, _, found := strings.Cut(id, ".")
+		if !found {
+			prefix = "id"
+		}
+		fake = fmt.Sprintf("%s.fake%d", prefix, len(s.ids)+1)
+	}
+	s.ids[id] = fake
+	return fake
+}
+
+func (s *Scrubber) fakeEmail(email string) string {
+	key := strings.ToLower(email)
+	if fake, ok := s.emails[key]; ok {
+		return fake
+	}
+	fake := fmt.Sprintf("user%d@example.com", len(s.emails)+1)
+	s.emails[key] = fake
+	return fake
+}
@@ -0,0 +1,158 @@
+package cassette_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/api/cassette"
+	"github.com/user/apo/internal/config"
+	"github.com/user/apo/internal/mockserver"
+)
+
+func TestScrubberIdentities(t *testing.T) {
+	const (
+		guid       = "3f2b8c1e-5d6a-4e7f-9a0b-1c2d3e4f5a6b"
+		descriptor = "aad.M2YyYjhjMWUtNWQ2YS03ZTdmLTlhMGItMWMyZDNlNGY1YTZi"
+	)
+	s := cassette.NewScrubber()
+	s.AddSecret("pat-value")
+
+	identity := s.Body([]byte(`{"displayName":"Ada Lovelace","uniqueName":"ada@contoso.com","id":"` + guid +
+		`","descriptor":"` + descriptor + `","url":"https://vssps.dev.azure.com/contoso/_apis/Identities/` + guid +
+		`","imageUrl":"https://dev.azure.com/contoso/_api/_common/identityImage?id=` + guid + `"}`))
+	again := s.Body([]byte(`{"value":[{"fields":{"System.AssignedTo":{"displayName":"Ada Lovelace","uniqueName":"ada@contoso.com","id":"` + guid + `"}}}]}`))
+	mention := s.String(`<a href="#" data-vss-mention="version:2.0,` + guid + `">@Ada Lovelace</a> token pat-value`)
+
+	for _, out := range []string{identity, again, mention} {
+		for _, leaked := range []string{guid, descriptor, "Ada Lovelace", "ada@contoso.com", "pat-value", "identityImage"} {
+			if strings.Contains(out, leaked) {
+				t.Errorf("scrubbed output %s still contains %q", out, leaked)
+			}
+		}
+	}
+
+	const fakeID = "00000000-0000-0000-0000-000000000001"
+	tests := []struct {
+		name, out, want string
+	}{
+		{"id", identity, `"id":"` + fakeID + `"`},
+		{"descriptor keeps its prefix", identity, `"descriptor":"aad.fake2"`},
+		{"url uses the fake id", identity, "/_apis/Identities/" + fakeID},
+		{"same person, same fakes", again, `"displayName":"User 1","id":"` + fakeID + `"`},
+		{"mention markup", mention, "version:2.0," + fakeID + `">@User 1</a>`},
+		{"secret", mention, "token REDACTED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(tt.out, tt.want) {
+				t.Errorf("got %s, want it to contain %s", tt.out, tt.want)
+			}
+		})
+	}
+}
+
+// TestScrubberNamesInText checks that a name in free text is scrubbed
+// whichever order the fields holding the text and the identity are visited
+// in. Go randomizes map iteration, so each body is scrubbed many times.
+func TestScrubberNamesInText(t *testing.T) {
+	const identity = `{"displayName":"Jane Roe","uniqueName":"jane@contoso.com","id":"9d7c1b2a-3e4f-4a5b-8c6d-7e8f9a0b1c2d"}`
+	bodies := []string{
+		`{"fields":{"System.History":"Moved back by Jane Roe","System.AssignedTo":` + identity + `}}`,
+		`{"fields":{"A.Comment":"Jane Roe asked for this","System.AssignedTo":` + identity + `,"Z.Notes":"see 9d7c1b2a-3e4f-4a5b-8c6d-7e8f9a0b1c2d"}}`,
+		`{"value":[{"text":"@Jane Roe please review"},{"createdBy":` + identity + `}]}`,
+		`{"comment":"Jane and Jane Roe","author":` + identity + `}`,
+	}
+	for _, body := range bodies {
+		for i := 0; i < 200; i++ {
+			out := cassette.NewScrubber().Body([]byte(body))
+			for _, leaked := range []string{"Jane Roe", "Roe", "jane@contoso.com", "9d7c1b2a-3e4f-4a5b-8c6d-7e8f9a0b1c2d"} {
+				if strings.Contains(out, leaked) {
+					t.Fatalf("run %d: scrubbed %s still contains %q", i, out, leaked)
+				}
+			}
+			if !strings.Contains(out, `"displayName":"User 1"`) {
+				t.Fatalf("run %d: scrubbed %s does not name the identity User 1", i, out)
+			}
+		}
+	}
+}
+
+// TestRecordReplay records a session against the mock server, checks that
+// the cassette holds no PAT or identity, and replays it with the server
+// gone.
+func TestRecordReplay(t *testing.T) {
+	const pat = "record-replay-pat"
+	srv, err := mockserver.New(mockserver.Options{PAT: pat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	cfg := &config.Config{Organization: "demo", Project: "demo", PAT: pat, APIURL: ts.URL, APIVersion: "7.1", DisableCache: true, MaxRetries: -1}
+	path := filepath.Join(t.TempDir(), "session.json")
+	ctx := context.Background()
+
+	rec, err := cassette.New(path, cassette.Record, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.AddSecret(pat)
+	client := api.NewClient(cfg)
+	client.SetTransport(rec)
+	recordedUser, err := client.ConnectionData(ctx)
+	if err != nil {
+		t.Fatalf("ConnectionData: %v", err)
+	}
+	recordedItems, err := client.GetMyWorkItems(ctx)
+	if err != nil {
+		t.Fatalf("GetMyWorkItems: %v", err)
+	}
+	ts.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{pat, recordedUser.AuthenticatedUser.ID, "Mock User", "mock.user@example.com", "Ada Lovelace"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("cassette contains %q", leaked)
+		}
+	}
+
+	rep, err := cassette.New(path, cassette.Replay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = api.NewClient(cfg)
+	client.SetTransport(rep)
+	replayedUser, err := client.ConnectionData(ctx)
+	if err != nil {
+		t.Fatalf("replayed ConnectionData: %v", err)
+	}
+	replayedItems, err := client.GetMyWorkItems(ctx)
+	if err != nil {
+		t.Fatalf("replayed GetMyWorkItems: %v", err)
+	}
+
+	if id := replayedUser.AuthenticatedUser.ID; id == "" || id == recordedUser.AuthenticatedUser.ID {
+		t.Errorf("replayed user ID = %q, want a scrubbed one", id)
+	}
+	if len(replayedItems) != len(recordedItems) || len(replayedItems) == 0 {
+		t.Fatalf("replayed %d work items, recorded %d", len(replayedItems), len(recordedItems))
+	}
+	for i, item := range replayedItems {
+		if item.ID != recordedItems[i].ID || item.Title() != recordedItems[i].Title() {
+			t.Errorf("item %d = #%d %q, want #%d %q", i, item.ID, item.Title(), recordedItems[i].ID, recordedItems[i].Title())
+		}
+		if strings.Contains(item.AssignedTo(), "Ada") {
+			t.Errorf("item #%d is assigned to %q, want a scrubbed name", item.ID, item.AssignedTo())
+		}
+	}
+	if _, err := client.ConnectionData(ctx); err == nil {
+		t.Error("a request with no recorded interaction left was answered")
+	}
+}
@@ -3,13 +3,14 @@ package api
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/user/apo/internal/config"
 	"github.com/user/apo/internal/domain"
@@ -17,71 +18,224 @@ import (
 
 // Client is the Azure DevOps API client.
 type Client struct {
-	baseURL    string
-	org        string
+	baseURL    string // organization (or Azure DevOps Server collection) URL
 	project    string
-	pat        string
-	apiVersion string
+	auth       Authenticator // shared with clients from ForProject
+	apiVersion *apiVersion   // shared with clients from ForProject
+	maxItems   int
+	retry      RetryPolicy
 	http       *http.Client
+	cache      *cacheTransport
+	limits     *limitState // shared with clients from ForProject
+}
+
+// limitState is the last rate-limit state the server reported.
+type limitState struct {
+	mu        sync.Mutex
+	rateLimit RateLimit
+	hasLimit  bool
 }
 
 // NewClient creates a new API client.
 func NewClient(cfg *config.Config) *Client {
-	return &Client{
-		baseURL:    cfg.APIURL,
-		org:        cfg.Organization,
+	retry := DefaultRetryPolicy()
+	if cfg.MaxRetries != 0 {
+		retry.MaxRetries = max(cfg.MaxRetries, 0)
+	}
+	c := &Client{
+		baseURL:    cfg.OrganizationURL(),
 		project:    cfg.Project,
-		pat:        cfg.PAT,
-		apiVersion: cfg.APIVersion,
+		auth:       NewAuthenticator(cfg),
+		apiVersion: &apiVersion{version: cfg.APIVersion},
+		maxItems:   cfg.MaxItems,
+		retry:      retry,
 		http:       &http.Client{Timeout: config.DefaultTimeout},
+		limits:     &limitState{},
+	}
+	if !cfg.DisableCache {
+		c.cache = newCacheTransport(config.GetCacheDir(), cacheScope(cfg), nil)
+		c.http.Transport = c.cache
 	}
+	return c
 }
 
-func (c *Client) do(method, url string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
-	if body != nil {
-		data, err := json.Marshal(body)
+// SetTransport replaces the HTTP transport used for requests, for example
+// with a cassette.Recorder. The response cache, if enabled, stays in front
+// of it.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	if c.cache != nil {
+		c.cache.next = rt
+		return
+	}
+	c.http.Transport = rt
+}
+
+// SetAuthenticator replaces how requests are authenticated.
+func (c *Client) SetAuthenticator(auth Authenticator) {
+	c.auth = auth
+}
+
+// RateLimit returns the most recent rate-limit state reported by the
+// server. The second result is false if the server has not reported one.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.limits.mu.Lock()
+	defer c.limits.mu.Unlock()
+	return c.limits.rateLimit, c.limits.hasLimit
+}
+
+// ForProject returns a client for another project in the same organization.
+// It shares this client's connection, cache and rate-limit state.
+func (c *Client) ForProject(project string) Service {
+	clone := *c
+	clone.project = project
+	return &clone
+}
+
+// call describes a single API request.
+type call struct {
+	method string
+	url    string
+	body   interface{}
+	result interface{}
+	// contentType overrides application/json, e.g. for JSON Patch.
+	contentType string
+	// retrySafe marks a non-idempotent method (POST) as free of side
+	// effects, such as a WIQL query, so it may be retried.
+	retrySafe bool
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body interface{}, result interface{}) error {
+	_, err := c.send(ctx, &call{method: method, url: url, body: body, result: result})
+	return err
+}
+
+// send performs a request, retrying transient failures according to the
+// client's retry policy, and returns the response headers on success.
+func (c *Client) send(ctx context.Context, cl *call) (http.Header, error) {
+	var data []byte
+	if cl.body != nil {
+		var err error
+		if data, err = json.Marshal(cl.body); err != nil {
+			return nil, fmt.Errorf("marshaling request: %w", err)
+		}
+	}
+	canRetry := cl.retrySafe || isIdempotent(cl.method)
+	reauthed, negotiated := false, false
+
+	for attempt := 0; ; attempt++ {
+		retriesLeft := attempt < c.retry.MaxRetries
+
+		auth, err := c.auth.Authorization(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("authenticating: %w", err)
+		}
+		resp, respBody, err := c.attempt(ctx, cl, data, auth)
 		if err != nil {
-			return fmt.Errorf("marshaling request: %w", err)
+			if canRetry && retriesLeft && ctx.Err() == nil {
+				if err := sleep(ctx, c.retry.backoff(attempt)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+		c.recordRateLimit(resp.Header)
+
+		// A rejected token may have expired early or been rotated: get a
+		// new one and try once more. The request was not processed, so this
+		// is safe regardless of method.
+		unauthorized := resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusNonAuthoritativeInfo
+		if unauthorized && !reauthed && c.auth.Reject(auth) {
+			reauthed = true
+			attempt--
+			continue
+		}
+
+		// An older server rejects API versions newer than it supports and
+		// names the latest it does; use that from now on.
+		if resp.StatusCode == http.StatusBadRequest && !negotiated {
+			if version, ok := supportedVersion(respBody); ok {
+				c.apiVersion.lower(version)
+				cl.url = withAPIVersion(cl.url, version+previewSuffix(cl.url))
+				negotiated = true
+				attempt--
+				continue
+			}
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 || resp.StatusCode == http.StatusNonAuthoritativeInfo {
+			// A 429 means the request was rejected before being processed,
+			// so it is safe to repeat regardless of method.
+			throttled := resp.StatusCode == http.StatusTooManyRequests
+			if retriesLeft && isRetryableStatus(resp.StatusCode) && (canRetry || throttled) {
+				wait, ok := retryAfter(resp.Header)
+				if !ok {
+					wait = c.retry.backoff(attempt)
+				}
+				if wait <= c.retry.MaxRetryAfter {
+					if err := sleep(ctx, wait); err != nil {
+						return nil, err
+					}
+					continue
+				}
+			}
+			return nil, newError(resp, respBody, cl.url)
+		}
+
+		if cl.result != nil {
+			if err := json.Unmarshal(respBody, cl.result); err != nil {
+				return nil, fmt.Errorf("parsing response: %w", err)
+			}
 		}
+		return resp.Header, nil
+	}
+}
+
+// attempt performs a single HTTP round trip and reads the full body.
+func (c *Client) attempt(ctx context.Context, cl *call, data []byte, auth string) (*http.Response, []byte, error) {
+	var bodyReader io.Reader
+	if data != nil {
 		bodyReader = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, cl.method, cl.url, bodyReader)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return nil, nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	contentType := cl.contentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Accept", "application/json")
-	auth := base64.StdEncoding.EncodeToString([]byte(":" + c.pat))
-	req.Header.Set("Authorization", "Basic "+auth)
+	req.Header.Set("Authorization", auth)
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return fmt.Errorf("executing request: %w", err)
+		return nil, nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("reading response: %w", err)
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, nil, fmt.Errorf("reading response: %w", err)
 	}
+	return resp, respBody, nil
+}
 
-	if result != nil {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("parsing response: %w", err)
-		}
+func (c *Client) recordRateLimit(h http.Header) {
+	rl, ok := parseRateLimit(h)
+	if !ok {
+		return
 	}
-	return nil
+	c.limits.mu.Lock()
+	c.limits.rateLimit, c.limits.hasLimit = rl, true
+	c.limits.mu.Unlock()
 }
 
 func (c *Client) url(path string, params ...string) string {
-	u := fmt.Sprintf("%s/%s/%s/%s?api-version=%s", c.baseURL, c.org, c.project, path, c.apiVersion)
+	u := fmt.Sprintf("%s/%s/%s?api-version=%s", c.baseURL, url.PathEscape(c.project), path, c.apiVersion.get())
 	for i := 0; i < len(params)-1; i += 2 {
 		u += fmt.Sprintf("&%s=%s", params[i], url.QueryEscape(params[i+1]))
 	}
@@ -89,7 +243,7 @@ func (c *Client) url(path string, params ...string) string {
 }
 
 func (c *Client) orgURL(path string, params ...string) string {
-	u := fmt.Sprintf("%s/%s/%s?api-version=%s", c.baseURL, c.org, path, c.apiVersion)
+	u := fmt.Sprintf("%s/%s?api-version=%s", c.baseURL, path, c.apiVersion.get())
 	for i := 0; i < len(params)-1; i += 2 {
 		u += fmt.Sprintf("&%s=%s", params[i], url.QueryEscape(params[i+1]))
 	}
@@ -97,37 +251,32 @@ func (c *Client) orgURL(path string, params ...string) string {
 }
 
 // GetMyWorkItems returns work items assigned to the current user.
-func (c *Client) GetMyWorkItems() ([]domain.WorkItem, error) {
-	wiql := `SELECT [System.Id] FROM WorkItems 
-             WHERE [System.AssignedTo] = @Me 
-             AND [System.State] <> 'Closed' 
-             AND [System.State] <> 'Removed'
-             ORDER BY [System.ChangedDate] DESC`
-
-	var result domain.WorkItemList
-	if err := c.do("POST", c.url("_apis/wit/wiql"), map[string]string{"query": wiql}, &result); err != nil {
-		return nil, err
-	}
-
-	if len(result.WorkItems) == 0 {
-		return []domain.WorkItem{}, nil
-	}
-
-	ids := make([]string, len(result.WorkItems))
-	for i, ref := range result.WorkItems {
-		ids[i] = fmt.Sprintf("%d", ref.ID)
-	}
+func (c *Client) GetMyWorkItems(ctx context.Context) ([]domain.WorkItem, error) {
+	return c.QueryWorkItems(ctx, MyWorkItemsQuery())
+}
 
-	var batch domain.WorkItemBatch
-	batchURL := c.url("_apis/wit/workitems", "ids", strings.Join(ids, ","))
-	if err := c.do("GET", batchURL, nil, &batch); err != nil {
-		return nil, err
+// MyWorkItemsQuery returns the WIQL for the current user's open work items,
+// most recently changed first. Given projects, it is limited to them, so a
+// single query covers several projects.
+func MyWorkItemsQuery(projects ...string) string {
+	var in string
+	if len(projects) > 0 {
+		quoted := make([]string, len(projects))
+		for i, p := range projects {
+			quoted[i] = "'" + strings.ReplaceAll(p, "'", "''") + "'"
+		}
+		in = "\n             AND [System.TeamProject] IN (" + strings.Join(quoted, ", ") + ")"
 	}
-	return batch.Value, nil
+	return `SELECT [System.Id] FROM WorkItems
+             WHERE [System.AssignedTo] = @Me
+             AND [System.State] <> 'Closed'
+             AND [System.State] <> 'Removed'` + in + `
+             ORDER BY [System.ChangedDate] DESC`
 }
 
-// ListBuilds returns recent builds.
-func (c *Client) ListBuilds(status, result string, top int) ([]domain.Build, error) {
+// Builds returns a pager over builds matching the given status and result
+// filters. Empty filters match everything.
+func (c *Client) Builds(ctx context.Context, status, result string, pageSize int) *Pager[domain.Build] {
 	params := []string{}
 	if status != "" {
 		params = append(params, "statusFilter", status)
@@ -135,64 +284,87 @@ func (c *Client) ListBuilds(status, result string, top int) ([]domain.Build, err
 	if result != "" {
 		params = append(params, "resultFilter", result)
 	}
-	if top > 0 {
-		params = append(params, "$top", fmt.Sprintf("%d", top))
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
 	}
+	return pagedList[domain.Build](ctx, c, "_apis/build/builds", false, pageSize, params...)
+}
 
-	var resp domain.BuildList
-	if err := c.do("GET", c.url("_apis/build/builds", params...), nil, &resp); err != nil {
-		return nil, err
+// ListBuilds returns recent builds, at most top of them. A top of zero
+// returns every build up to the client's item cap.
+func (c *Client) ListBuilds(ctx context.Context, status, result string, top int) ([]domain.Build, error) {
+	if top <= 0 {
+		return c.Builds(ctx, status, result, defaultPageSize).All(c.maxItems)
 	}
-	return resp.Value, nil
+	return c.Builds(ctx, status, result, min(top, defaultPageSize)).All(top)
 }
 
 // GetFailedBuilds returns recently failed builds.
-func (c *Client) GetFailedBuilds(top int) ([]domain.Build, error) {
-	return c.ListBuilds("completed", "failed", top)
+func (c *Client) GetFailedBuilds(ctx context.Context, top int) ([]domain.Build, error) {
+	return c.ListBuilds(ctx, "completed", "failed", top)
 }
 
 // GetRunningBuilds returns currently running builds.
-func (c *Client) GetRunningBuilds() ([]domain.Build, error) {
-	return c.ListBuilds("inProgress", "", 50)
+func (c *Client) GetRunningBuilds(ctx context.Context) ([]domain.Build, error) {
+	return c.ListBuilds(ctx, "inProgress", "", 50)
 }
 
-// ListPipelines returns all pipelines.
-func (c *Client) ListPipelines() ([]domain.Pipeline, error) {
-	var resp domain.PipelineList
-	if err := c.do("GET", c.url("_apis/pipelines"), nil, &resp); err != nil {
-		return nil, err
-	}
-	return resp.Value, nil
+// Pipelines returns a pager over all pipelines.
+func (c *Client) Pipelines(ctx context.Context) *Pager[domain.Pipeline] {
+	return pagedList[domain.Pipeline](ctx, c, "_apis/pipelines", false, defaultPageSize)
 }
 
-// ListRepositories returns all Git repositories.
-func (c *Client) ListRepositories() ([]domain.Repository, error) {
-	var resp domain.RepositoryList
-	if err := c.do("GET", c.url("_apis/git/repositories"), nil, &resp); err != nil {
-		return nil, err
-	}
-	return resp.Value, nil
+// ListPipelines returns all pipelines, up to the client's item cap.
+func (c *Client) ListPipelines(ctx context.Context) ([]domain.Pipeline, error) {
+	return c.Pipelines(ctx).All(c.maxItems)
 }
 
-// GetActivePullRequests returns active pull requests.
-func (c *Client) GetActivePullRequests(top int) ([]domain.PullRequest, error) {
-	params := []string{"searchCriteria.status", "active"}
-	if top > 0 {
-		params = append(params, "$top", fmt.Sprintf("%d", top))
-	}
+// Repositories returns a pager over all Git repositories. The endpoint
+// ignores $top/$skip, so only continuation tokens are followed.
+func (c *Client) Repositories(ctx context.Context) *Pager[domain.Repository] {
+	return pagedList[domain.Repository](ctx, c, "_apis/git/repositories", false, 0)
+}
 
-	var resp domain.PullRequestList
-	if err := c.do("GET", c.url("_apis/git/pullrequests", params...), nil, &resp); err != nil {
-		return nil, err
+// ListRepositories returns all Git repositories, up to the client's item cap.
+func (c *Client) ListRepositories(ctx context.Context) ([]domain.Repository, error) {
+	return c.Repositories(ctx).All(c.maxItems)
+}
+
+// PullRequests returns a pager over pull requests with the given status.
+func (c *Client) PullRequests(ctx context.Context, status string, pageSize int) *Pager[domain.PullRequest] {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
 	}
-	return resp.Value, nil
+	return pagedList[domain.PullRequest](ctx, c, "_apis/git/pullrequests", false, pageSize,
+		"searchCriteria.status", status)
 }
 
-// ListProjects returns all projects in the organization.
-func (c *Client) ListProjects() ([]domain.Project, error) {
-	var resp domain.ProjectList
-	if err := c.do("GET", c.orgURL("_apis/projects"), nil, &resp); err != nil {
-		return nil, err
+// GetActivePullRequests returns active pull requests, at most top of them.
+// A top of zero returns every active PR up to the client's item cap.
+func (c *Client) GetActivePullRequests(ctx context.Context, top int) ([]domain.PullRequest, error) {
+	if top <= 0 {
+		return c.PullRequests(ctx, "active", defaultPageSize).All(c.maxItems)
 	}
-	return resp.Value, nil
+	return c.PullRequests(ctx, "active", min(top, defaultPageSize)).All(top)
+}
+
+// ConnectionData returns the server's description of the connection,
+// including the authenticated identity. The endpoint only exists as a
+// preview version.
+func (c *Client) ConnectionData(ctx context.Context) (domain.ConnectionData, error) {
+	var data domain.ConnectionData
+	u := withAPIVersion(c.orgURL("_apis/connectionData"), c.apiVersion.get()+"-preview")
+	err := c.do(ctx, "GET", u, nil, &data)
+	return data, err
+}
+
+// Projects returns a pager over all projects in the organization.
+func (c *Client) Projects(ctx context.Context) *Pager[domain.Project] {
+	return pagedList[domain.Project](ctx, c, "_apis/projects", true, defaultPageSize)
+}
+
+// ListProjects returns all projects in the organization, up to the client's
+// item cap.
+func (c *Client) ListProjects(ctx context.Context) ([]domain.Project, error) {
+	return c.Projects(ctx).All(c.maxItems)
 }
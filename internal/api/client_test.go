@@ -0,0 +1,326 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/config"
+	"github.com/user/apo/internal/mockserver"
+)
+
+const testPAT = "client-test-pat"
+
+// snapshot is what a session of list calls returned, reduced to IDs so
+// that runs against differently configured mock servers can be compared.
+type snapshot struct {
+	user         string
+	projects     []string
+	builds       []int
+	failed       []int
+	pipelines    []int
+	repositories []string
+	pullRequests []int
+	workItems    []int
+}
+
+// session runs every list call the TUI makes through the real client.
+func session(ctx context.Context, c *api.Client) (snapshot, error) {
+	var s snapshot
+	conn, err := c.ConnectionData(ctx)
+	if err != nil {
+		return s, err
+	}
+	s.user = conn.AuthenticatedUser.ID
+
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		return s, err
+	}
+	for _, p := range projects {
+		s.projects = append(s.projects, p.Name)
+	}
+	builds, err := c.ListBuilds(ctx, "", "", 0)
+	if err != nil {
+		return s, err
+	}
+	for _, b := range builds {
+		s.builds = append(s.builds, b.ID)
+	}
+	failed, err := c.GetFailedBuilds(ctx, 0)
+	if err != nil {
+		return s, err
+	}
+	for _, b := range failed {
+		s.failed = append(s.failed, b.ID)
+	}
+	pipelines, err := c.ListPipelines(ctx)
+	if err != nil {
+		return s, err
+	}
+	for _, p := range pipelines {
+		s.pipelines = append(s.pipelines, p.ID)
+	}
+	repos, err := c.ListRepositories(ctx)
+	if err != nil {
+		return s, err
+	}
+	for _, r := range repos {
+		s.repositories = append(s.repositories, r.Name)
+	}
+	prs, err := c.GetActivePullRequests(ctx, 0)
+	if err != nil {
+		return s, err
+	}
+	for _, pr := range prs {
+		s.pullRequests = append(s.pullRequests, pr.PullRequestID)
+	}
+	items, err := c.GetMyWorkItems(ctx)
+	if err != nil {
+		return s, err
+	}
+	for _, item := range items {
+		s.workItems = append(s.workItems, item.ID)
+	}
+	return s, nil
+}
+
+// newClient starts a mock server with opts and returns a client for it,
+// along with the api-version of every request the server received. The
+// server expects testPAT unless opts names another.
+func newClient(t *testing.T, opts mockserver.Options, maxRetries int) (*api.Client, func() []string) {
+	t.Helper()
+	if opts.PAT == "" {
+		opts.PAT = testPAT
+	}
+	srv, err := mockserver.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu       sync.Mutex
+		versions []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		versions = append(versions, r.URL.Query().Get("api-version"))
+		mu.Unlock()
+		srv.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	cfg := &config.Config{Organization: "demo", Project: "demo", PAT: testPAT, APIURL: ts.URL, APIVersion: "7.1", DisableCache: true, MaxRetries: maxRetries}
+	return api.NewClient(cfg), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(versions)
+	}
+}
+
+func TestClientAgainstMockServer(t *testing.T) {
+	ctx := context.Background()
+	base, _ := newClient(t, mockserver.Options{}, -1)
+	want, err := session(ctx, base)
+	if err != nil {
+		t.Fatalf("baseline session: %v", err)
+	}
+	if len(want.builds) <= 3 || len(want.pipelines) <= 3 || len(want.workItems) == 0 {
+		t.Fatalf("baseline too small to exercise paging: %+v", want)
+	}
+
+	tests := []struct {
+		name       string
+		opts       mockserver.Options
+		maxRetries int
+		// check inspects what the server saw, beyond the session matching
+		// the baseline.
+		check func(t *testing.T, c *api.Client, versions []string)
+	}{
+		{
+			name:       "pages of three",
+			opts:       mockserver.Options{PageSize: 3},
+			maxRetries: -1,
+		},
+		{
+			name:       "single item pages",
+			opts:       mockserver.Options{PageSize: 1},
+			maxRetries: -1,
+		},
+		{
+			name: "throttled and retried",
+			opts: mockserver.Options{ThrottleEvery: 6},
+			check: func(t *testing.T, c *api.Client, _ []string) {
+				rl, ok := c.RateLimit()
+				if !ok || rl.Resource != "Core" || rl.Remaining != 0 {
+					t.Errorf("RateLimit() = %+v, %v, want the throttled Core limit", rl, ok)
+				}
+			},
+		},
+		{
+			name:       "server limited to 6.0",
+			opts:       mockserver.Options{MaxAPIVersion: "6.0", PageSize: 10},
+			maxRetries: -1,
+			check: func(t *testing.T, _ *api.Client, versions []string) {
+				// The first request finds out; every later one asks for 6.0.
+				if len(versions) < 2 || versions[0] != "7.1-preview" || versions[1] != "6.0-preview" {
+					t.Fatalf("first api-versions = %q, want 7.1-preview then 6.0-preview", versions[:min(len(versions), 2)])
+				}
+				for _, v := range versions[2:] {
+					if v != "6.0" && v != "6.0-preview" {
+						t.Errorf("request sent with api-version %q after negotiating 6.0", v)
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, versions := newClient(t, tt.opts, tt.maxRetries)
+			got, err := session(ctx, c)
+			if err != nil {
+				t.Fatalf("session: %v", err)
+			}
+			compare(t, got, want)
+			if tt.check != nil {
+				tt.check(t, c, versions())
+			}
+		})
+	}
+}
+
+func compare(t *testing.T, got, want snapshot) {
+	t.Helper()
+	if got.user != want.user {
+		t.Errorf("user = %q, want %q", got.user, want.user)
+	}
+	if !slices.Equal(got.projects, want.projects) {
+		t.Errorf("projects = %v, want %v", got.projects, want.projects)
+	}
+	if !slices.Equal(got.builds, want.builds) {
+		t.Errorf("builds = %v, want %v", got.builds, want.builds)
+	}
+	if !slices.Equal(got.failed, want.failed) {
+		t.Errorf("failed builds = %v, want %v", got.failed, want.failed)
+	}
+	if !slices.Equal(got.pipelines, want.pipelines) {
+		t.Errorf("pipelines = %v, want %v", got.pipelines, want.pipelines)
+	}
+	if !slices.Equal(got.repositories, want.repositories) {
+		t.Errorf("repositories = %v, want %v", got.repositories, want.repositories)
+	}
+	if !slices.Equal(got.pullRequests, want.pullRequests) {
+		t.Errorf("pull requests = %v, want %v", got.pullRequests, want.pullRequests)
+	}
+	if !slices.Equal(got.workItems, want.workItems) {
+		t.Errorf("work items = %v, want %v", got.workItems, want.workItems)
+	}
+}
+
+func TestMyWorkItemsQueryAcrossProjects(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newClient(t, mockserver.Options{}, -1)
+	tests := []struct {
+		projects []string
+		want     []string
+	}{
+		{nil, []string{"demo"}},
+		{[]string{"platform"}, []string{"platform"}},
+		{[]string{"demo", "platform"}, []string{"demo", "platform"}},
+		{[]string{"o'brien"}, nil},
+	}
+	for _, tt := range tests {
+		items, err := c.QueryWorkItems(ctx, api.MyWorkItemsQuery(tt.projects...))
+		if err != nil {
+			t.Fatalf("projects %q: %v", tt.projects, err)
+		}
+		var got []string
+		for _, item := range items {
+			if p := item.TeamProject(); !slices.Contains(got, p) {
+				got = append(got, p)
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("projects %q: items from %q, want %q", tt.projects, got, tt.want)
+		}
+	}
+}
+
+func TestClientErrorsFromMockServer(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		opts mockserver.Options
+		call func(c *api.Client) error
+		is   func(error) bool // nil when the call should succeed
+		want string           // text api.Explain must show, if any
+	}{
+		{
+			name: "build area denied",
+			opts: mockserver.Options{DenyAreas: []string{"build"}},
+			call: func(c *api.Client) error { _, err := c.ListPipelines(ctx); return err },
+			is:   api.IsForbidden,
+			want: "check the PAT's scopes",
+		},
+		{
+			name: "code area denied",
+			opts: mockserver.Options{DenyAreas: []string{"code"}},
+			call: func(c *api.Client) error { _, err := c.GetActivePullRequests(ctx, 10); return err },
+			is:   api.IsForbidden,
+			want: "check the PAT's scopes",
+		},
+		{
+			name: "wrong PAT",
+			opts: mockserver.Options{PAT: "another-pat"},
+			call: func(c *api.Client) error { _, err := c.ListProjects(ctx); return err },
+			is:   api.IsUnauthorized,
+			want: "PAT expired or invalid",
+		},
+		{
+			name: "other areas still allowed",
+			opts: mockserver.Options{DenyAreas: []string{"build"}},
+			call: func(c *api.Client) error { _, err := c.GetMyWorkItems(ctx); return err },
+		},
+		{
+			name: "throttled without retries",
+			opts: mockserver.Options{ThrottleEvery: 1},
+			call: func(c *api.Client) error { _, err := c.ListProjects(ctx); return err },
+			is:   api.IsThrottled,
+		},
+		{
+			name: "stale revision",
+			call: func(c *api.Client) error {
+				item, err := c.GetWorkItem(ctx, 1001)
+				if err != nil {
+					return err
+				}
+				if _, err := c.UpdateWorkItem(ctx, item.ID, item.Rev, map[string]interface{}{"System.Title": "First"}); err != nil {
+					return err
+				}
+				_, err = c.UpdateWorkItem(ctx, item.ID, item.Rev, map[string]interface{}{"System.Title": "Second"})
+				return err
+			},
+			is: api.IsConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newClient(t, tt.opts, -1)
+			err := tt.call(c)
+			if tt.is != nil && !tt.is(err) {
+				t.Errorf("error = %v, want it to match", err)
+			}
+			if tt.is == nil && err != nil {
+				t.Errorf("error = %v, want none", err)
+			}
+			if got := api.Explain(err); !strings.Contains(got, tt.want) {
+				t.Errorf("Explain = %q, want it to say %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/user/apo/internal/domain"
+)
+
+// commentsPageSize is the $top used when listing comments; the service
+// allows at most 200.
+const commentsPageSize = 200
+
+// WorkItemComments returns the discussion of a work item, oldest first,
+// without deleted comments.
+func (c *Client) WorkItemComments(ctx context.Context, id int) ([]domain.Comment, error) {
+	pages := NewPager(0, func(token string, _ int) ([]domain.Comment, string, error) {
+		params := []string{"$top", fmt.Sprint(commentsPageSize), "order", "asc"}
+		if token != "" {
+			params = append(params, "continuationToken", token)
+		}
+		var page domain.CommentList
+		err := c.do(ctx, "GET", c.commentsURL(id, params...), nil, &page)
+		return page.Comments, page.ContinuationToken, err
+	})
+	all, err := pages.All(0)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := all[:0]
+	for _, comment := range all {
+		if !comment.IsDeleted {
+			comments = append(comments, comment)
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedDate.Before(comments[j].CreatedDate)
+	})
+	return comments, nil
+}
+
+// AddWorkItemComment posts a comment, given as HTML, to a work item's
+// discussion. See CommentHTML for turning typed text into HTML.
+func (c *Client) AddWorkItemComment(ctx context.Context, id int, text string) (domain.Comment, error) {
+	var comment domain.Comment
+	body := map[string]string{"text": text}
+	err := c.do(ctx, "POST", c.commentsURL(id), body, &comment)
+	return comment, err
+}
+
+// commentsURL returns the URL of a work item's comments. The endpoint
+// only exists as a preview version.
+func (c *Client) commentsURL(id int, params ...string) string {
+	u := c.url(fmt.Sprintf("_apis/wit/workitems/%d/comments", id), params...)
+	return withAPIVersion(u, c.apiVersion.get()+"-preview")
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Error is a non-successful response from the Azure DevOps REST API.
+type Error struct {
+	StatusCode int
+	TypeKey    string // e.g. "ProjectDoesNotExistWithNameException"
+	Message    string
+	ActivityID string
+	URL        string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.TypeKey != "" {
+		return fmt.Sprintf("API error (status %d, %s): %s", e.StatusCode, e.TypeKey, msg)
+	}
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, msg)
+}
+
+// newError builds an Error from a failed response. Azure DevOps error bodies
+// are JSON objects with message and typeKey; anything else (such as the
+// HTML sign-in page returned for a bad PAT) is reduced to its status text.
+func newError(resp *http.Response, body []byte, url string) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		ActivityID: resp.Header.Get("ActivityId"),
+		URL:        url,
+	}
+	if e.ActivityID == "" {
+		e.ActivityID = resp.Header.Get("X-VSS-E2EID")
+	}
+
+	var payload struct {
+		Message string `json:"message"`
+		TypeKey string `json:"typeKey"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		e.Message = payload.Message
+		e.TypeKey = payload.TypeKey
+	} else if text := strings.TrimSpace(string(body)); text != "" && !strings.HasPrefix(text, "<") {
+		e.Message = text
+	}
+
+	// Azure DevOps answers an invalid PAT with 203 and a sign-in page.
+	if e.StatusCode == http.StatusNonAuthoritativeInfo {
+		e.StatusCode = http.StatusUnauthorized
+	}
+	return e
+}
+
+func hasStatus(err error, codes ...int) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnauthorized reports whether err is an authentication failure, usually
+// an expired or revoked PAT.
+func IsUnauthorized(err error) bool { return hasStatus(err, http.StatusUnauthorized) }
+
+// IsForbidden reports whether err is an authorization failure, usually a PAT
+// missing a scope.
+func IsForbidden(err error) bool { return hasStatus(err, http.StatusForbidden) }
+
+// IsNotFound reports whether err is a missing resource, such as an unknown
+// project.
+func IsNotFound(err error) bool { return hasStatus(err, http.StatusNotFound) }
+
+// testFailedTypeKey is the typeKey of the 400 Bad Request Azure DevOps
+// answers a JSON Patch document with when one of its test operations, such
+// as the test of /rev that guards work item updates, fails.
+const testFailedTypeKey = "TestPatchOperationFailedException"
+
+// IsConflict reports whether err is a rejected update because the resource
+// changed since it was read, such as a work item revision mismatch: a 409
+// or 412, a revision mismatch, or a failed JSON Patch test operation.
+func IsConflict(err error) bool {
+	if hasStatus(err, http.StatusConflict, http.StatusPreconditionFailed) {
+		return true
+	}
+	var apiErr *Error
+	return errors.As(err, &apiErr) &&
+		(strings.Contains(apiErr.TypeKey, "RevisionMismatch") || apiErr.TypeKey == testFailedTypeKey)
+}
+
+// IsThrottled reports whether err is a rate-limit rejection that outlasted
+// the client's retries.
+func IsThrottled(err error) bool { return hasStatus(err, http.StatusTooManyRequests) }
+
+// IsUnreachable reports whether err is a network failure, meaning Azure
+// DevOps could not be reached at all.
+func IsUnreachable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && !errors.Is(err, context.Canceled)
+}
+
+// Summary returns a compact description of err suited to a one-line
+// section header, such as "403 Forbidden".
+func Summary(err error) string {
+	var apiErr *Error
+	switch {
+	case err == nil:
+		return ""
+	case IsUnreachable(err):
+		return "unreachable"
+	case errors.As(err, &apiErr):
+		return fmt.Sprintf("%d %s", apiErr.StatusCode, http.StatusText(apiErr.StatusCode))
+	}
+	return Explain(err)
+}
+
+// Explain returns a short, user-facing description of err.
+func Explain(err error) string {
+	var apiErr *Error
+	switch {
+	case err == nil:
+		return ""
+	case IsUnreachable(err):
+		return "Azure DevOps is unreachable — check your network or VPN"
+	case IsUnauthorized(err):
+		return "PAT expired or invalid — run 'apo config'"
+	case IsForbidden(err):
+		return "Access denied — check the PAT's scopes"
+	case IsThrottled(err):
+		return "Azure DevOps is throttling requests — try again shortly"
+	case IsConflict(err):
+		return "Someone else changed it since it was loaded — reload and try again"
+	case IsNotFound(err):
+		errors.As(err, &apiErr)
+		if strings.Contains(apiErr.TypeKey, "Project") {
+			return "Project not found — run 'apo config'"
+		}
+		if apiErr.Message != "" {
+			return "Not found: " + apiErr.Message
+		}
+		return "Not found"
+	case errors.As(err, &apiErr):
+		if apiErr.Message != "" {
+			return apiErr.Message
+		}
+		return apiErr.Error()
+	}
+	return err.Error()
+}
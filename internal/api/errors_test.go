@@ -0,0 +1,36 @@
+package api_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/user/apo/internal/api"
+)
+
+func TestIsConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"409", &api.Error{StatusCode: http.StatusConflict}, true},
+		{"412", &api.Error{StatusCode: http.StatusPreconditionFailed}, true},
+		{"revision mismatch", &api.Error{StatusCode: http.StatusBadRequest, TypeKey: "WorkItemRevisionMismatchException"}, true},
+		{"failed test of /rev", &api.Error{StatusCode: http.StatusBadRequest, TypeKey: "TestPatchOperationFailedException",
+			Message: "Test operation failed for path '/rev': the value 4 does not match the expected value 3."}, true},
+		{"wrapped", fmt.Errorf("updating #7: %w", &api.Error{StatusCode: http.StatusBadRequest, TypeKey: "TestPatchOperationFailedException"}), true},
+		{"other 400", &api.Error{StatusCode: http.StatusBadRequest, TypeKey: "WorkItemFieldInvalidException"}, false},
+		{"404", &api.Error{StatusCode: http.StatusNotFound}, false},
+		{"not an API error", errors.New("conflict"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := api.IsConflict(tt.err); got != tt.want {
+				t.Errorf("IsConflict(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,679 @@
+// Package fake provides an in-memory api.Service for tests.
+//
+// Seed the fake with domain values, then hand it to anything that accepts an
+// api.Service:
+//
+//	svc := fake.New()
+//	svc.SeedBuilds(domain.Build{ID: 1, Status: "completed", Result: "failed"})
+//	ag := agent.New(svc)
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/domain"
+)
+
+// Client is an in-memory Azure DevOps backend. It is safe for concurrent use.
+type Client struct {
+	mu           sync.RWMutex
+	workItems    []domain.WorkItem
+	builds       []domain.Build
+	pipelines    []domain.Pipeline
+	repos        []domain.Repository
+	pullRequests []domain.PullRequest
+	projects     []domain.Project
+	errs         map[string]error
+	rateLimit    *api.RateLimit
+	connection   domain.ConnectionData
+	queries      []domain.Query
+	queryResults map[string][]int // work item IDs by WIQL
+	states       map[string][]domain.WorkItemState
+	types        []domain.WorkItemType
+	comments     map[int][]domain.Comment
+	updates      map[int][]domain.WorkItemUpdate
+	identities   []domain.Identity
+}
+
+// defaultTypes are the work item types without seeded ones: those of the
+// Agile process, each requiring only a title.
+var defaultTypes = func() []domain.WorkItemType {
+	var types []domain.WorkItemType
+	for _, name := range []string{"Bug", "Epic", "Feature", "Issue", "Task", "User Story"} {
+		types = append(types, domain.WorkItemType{
+			Name: name,
+			Fields: []domain.WorkItemTypeField{
+				{ReferenceName: "System.Title", Name: "Title", AlwaysRequired: true},
+				{ReferenceName: "System.State", Name: "State", AlwaysRequired: true, DefaultValue: "New"},
+				{ReferenceName: "System.AssignedTo", Name: "Assigned To"},
+				{ReferenceName: "System.Description", Name: "Description"},
+			},
+		})
+	}
+	return types
+}()
+
+// defaultStates are the states of every work item type without seeded ones,
+// those of the Agile process's Bug.
+var defaultStates = []domain.WorkItemState{
+	{Name: "New", Category: "Proposed"},
+	{Name: "Active", Category: "InProgress"},
+	{Name: "Resolved", Category: "Resolved"},
+	{Name: "Closed", Category: "Completed"},
+}
+
+var _ api.Service = (*Client)(nil)
+
+// New returns an empty fake, authenticated as "Fake User".
+func New() *Client {
+	return &Client{
+		errs:         make(map[string]error),
+		queryResults: make(map[string][]int),
+		states:       make(map[string][]domain.WorkItemState),
+		comments:     make(map[int][]domain.Comment),
+		updates:      make(map[int][]domain.WorkItemUpdate),
+		connection: domain.ConnectionData{
+			AuthenticatedUser: domain.ConnectionUser{
+				ID:                  "00000000-0000-0000-0000-000000000001",
+				ProviderDisplayName: "Fake User",
+				Properties:          map[string]domain.PropertyValue{"Account": {Value: "fake@example.com"}},
+			},
+			DeploymentType: "hosted",
+		},
+	}
+}
+
+// SeedWorkItems adds work items. Every seeded item counts as assigned to the
+// caller.
+func (c *Client) SeedWorkItems(items ...domain.WorkItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.workItems = append(c.workItems, items...)
+}
+
+// SeedBuilds adds builds. List calls return them in seeded order.
+func (c *Client) SeedBuilds(builds ...domain.Build) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.builds = append(c.builds, builds...)
+}
+
+// SeedPipelines adds pipelines.
+func (c *Client) SeedPipelines(pipelines ...domain.Pipeline) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pipelines = append(c.pipelines, pipelines...)
+}
+
+// SeedRepositories adds repositories.
+func (c *Client) SeedRepositories(repos ...domain.Repository) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.repos = append(c.repos, repos...)
+}
+
+// SeedPullRequests adds pull requests.
+func (c *Client) SeedPullRequests(prs ...domain.PullRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pullRequests = append(c.pullRequests, prs...)
+}
+
+// SeedProjects adds projects.
+func (c *Client) SeedProjects(projects ...domain.Project) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.projects = append(c.projects, projects...)
+}
+
+// SeedQueries adds saved queries. Folders are not flattened.
+func (c *Client) SeedQueries(queries ...domain.Query) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, queries...)
+}
+
+// SetQueryResult makes the given WIQL, whether run directly or through a
+// saved query, return the seeded work items with these IDs. WIQL without a
+// result returns every seeded work item.
+func (c *Client) SetQueryResult(wiql string, ids ...int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queryResults[wiql] = ids
+}
+
+// SeedStates sets the states of a work item type.
+func (c *Client) SeedStates(workItemType string, states ...domain.WorkItemState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states[workItemType] = states
+}
+
+// SeedWorkItemTypes sets the work item types, replacing the defaults.
+func (c *Client) SeedWorkItemTypes(types ...domain.WorkItemType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types = append(c.types, types...)
+}
+
+// SeedComments adds comments to a work item's discussion, which is returned
+// in seeded order.
+func (c *Client) SeedComments(workItemID int, comments ...domain.Comment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.comments[workItemID] = append(c.comments[workItemID], comments...)
+}
+
+// SeedUpdates adds revisions to a work item's history, which is returned
+// in seeded order.
+func (c *Client) SeedUpdates(workItemID int, updates ...domain.WorkItemUpdate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates[workItemID] = append(c.updates[workItemID], updates...)
+}
+
+// SeedIdentities adds users that SearchIdentities finds.
+func (c *Client) SeedIdentities(identities ...domain.Identity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.identities = append(c.identities, identities...)
+}
+
+// SetConnectionData sets the value returned by ConnectionData.
+func (c *Client) SetConnectionData(data domain.ConnectionData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connection = data
+}
+
+// Fail makes the named method (e.g. "ListPipelines") return err until
+// cleared with a nil err. Pagers fail with the error of their List method.
+func (c *Client) Fail(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.errs, method)
+		return
+	}
+	c.errs[method] = err
+}
+
+// SetRateLimit sets the value reported by RateLimit.
+func (c *Client) SetRateLimit(rl api.RateLimit) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimit = &rl
+}
+
+// RateLimit returns the value set with SetRateLimit.
+func (c *Client) RateLimit() (api.RateLimit, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.rateLimit == nil {
+		return api.RateLimit{}, false
+	}
+	return *c.rateLimit, true
+}
+
+func (c *Client) check(ctx context.Context, method string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.errs[method]
+}
+
+// notFound returns the error the service gives for a missing resource.
+func notFound(what string) error {
+	return &api.Error{StatusCode: http.StatusNotFound, Message: what + " does not exist"}
+}
+
+// GetMyWorkItems returns seeded work items that are not Closed or Removed.
+func (c *Client) GetMyWorkItems(ctx context.Context) ([]domain.WorkItem, error) {
+	if err := c.check(ctx, "GetMyWorkItems"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	items := []domain.WorkItem{}
+	for _, item := range c.workItems {
+		if state := item.State(); state != "Closed" && state != "Removed" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// QueryWorkItems returns the result set for wiql with SetQueryResult, or
+// every seeded work item.
+func (c *Client) QueryWorkItems(ctx context.Context, wiql string) ([]domain.WorkItem, error) {
+	if err := c.check(ctx, "QueryWorkItems"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.queryResult(wiql), nil
+}
+
+func (c *Client) queryResult(wiql string) []domain.WorkItem {
+	ids, ok := c.queryResults[wiql]
+	if !ok {
+		return append([]domain.WorkItem{}, c.workItems...)
+	}
+	items := []domain.WorkItem{}
+	for _, id := range ids {
+		for _, item := range c.workItems {
+			if item.ID == id {
+				items = append(items, item)
+				break
+			}
+		}
+	}
+	return items
+}
+
+// ListQueries returns the seeded queries that are not folders.
+func (c *Client) ListQueries(ctx context.Context) ([]domain.Query, error) {
+	if err := c.check(ctx, "ListQueries"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	queries := []domain.Query{}
+	for _, q := range c.queries {
+		if !q.IsFolder {
+			queries = append(queries, q)
+		}
+	}
+	return queries, nil
+}
+
+// GetQuery returns the seeded query with the given ID or path.
+func (c *Client) GetQuery(ctx context.Context, idOrPath string) (domain.Query, error) {
+	if err := c.check(ctx, "GetQuery"); err != nil {
+		return domain.Query{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	q, ok := c.findQuery(idOrPath)
+	if !ok {
+		return domain.Query{}, notFound("query " + idOrPath)
+	}
+	return q, nil
+}
+
+// RunQuery returns the result set of the seeded query's WIQL.
+func (c *Client) RunQuery(ctx context.Context, idOrPath string) ([]domain.WorkItem, error) {
+	if err := c.check(ctx, "RunQuery"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	q, ok := c.findQuery(idOrPath)
+	if !ok {
+		return nil, notFound("query " + idOrPath)
+	}
+	return c.queryResult(q.Wiql), nil
+}
+
+func (c *Client) findQuery(idOrPath string) (domain.Query, bool) {
+	for _, q := range c.queries {
+		if strings.EqualFold(q.ID, idOrPath) || strings.EqualFold(q.Path, strings.Trim(idOrPath, "/")) {
+			return q, true
+		}
+	}
+	return domain.Query{}, false
+}
+
+// GetWorkItem returns the seeded work item with the given ID.
+func (c *Client) GetWorkItem(ctx context.Context, id int) (domain.WorkItem, error) {
+	if err := c.check(ctx, "GetWorkItem"); err != nil {
+		return domain.WorkItem{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, item := range c.workItems {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return domain.WorkItem{}, notFound(fmt.Sprintf("work item %d", id))
+}
+
+// UpdateWorkItem sets fields on a seeded work item, increments its
+// revision and records the change in its updates. When rev is not the
+// item's current revision it fails as the service does when the test of
+// /rev fails: 400 with TestPatchOperationFailedException.
+func (c *Client) UpdateWorkItem(ctx context.Context, id, rev int, fields map[string]interface{}) (domain.WorkItem, error) {
+	if err := c.check(ctx, "UpdateWorkItem"); err != nil {
+		return domain.WorkItem{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, item := range c.workItems {
+		if item.ID != id {
+			continue
+		}
+		if item.Rev != rev {
+			return domain.WorkItem{}, &api.Error{
+				StatusCode: http.StatusBadRequest,
+				TypeKey:    "TestPatchOperationFailedException",
+				Message:    fmt.Sprintf("Test operation failed for path '/rev': the value %d does not match the expected value %d.", item.Rev, rev),
+			}
+		}
+		updated := make(map[string]interface{}, len(item.Fields)+len(fields))
+		for name, value := range item.Fields {
+			updated[name] = value
+		}
+		changes := make(map[string]domain.FieldChange, len(fields))
+		for name, value := range fields {
+			changes[name] = domain.FieldChange{OldValue: item.Fields[name], NewValue: value}
+			updated[name] = value
+		}
+		item.Fields = updated
+		item.Rev++
+		c.workItems[i] = item
+		user := c.connection.AuthenticatedUser
+		c.updates[id] = append(c.updates[id], domain.WorkItemUpdate{
+			ID:          len(c.updates[id]) + 1,
+			WorkItemID:  id,
+			Rev:         item.Rev,
+			RevisedBy:   domain.Identity{ID: user.ID, DisplayName: user.ProviderDisplayName, UniqueName: user.Account()},
+			RevisedDate: time.Now(),
+			Fields:      changes,
+		})
+		return item, nil
+	}
+	return domain.WorkItem{}, notFound(fmt.Sprintf("work item %d", id))
+}
+
+// CreateWorkItem adds a work item with the next free ID, in state New
+// unless fields say otherwise. A parent is recorded in System.Parent.
+func (c *Client) CreateWorkItem(ctx context.Context, workItemType string, fields map[string]interface{}, parent int) (domain.WorkItem, error) {
+	if err := c.check(ctx, "CreateWorkItem"); err != nil {
+		return domain.WorkItem{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := 1
+	for _, item := range c.workItems {
+		id = max(id, item.ID+1)
+	}
+	item := domain.WorkItem{ID: id, Rev: 1, Fields: map[string]interface{}{
+		"System.Id":           float64(id),
+		"System.WorkItemType": workItemType,
+		"System.State":        "New",
+	}}
+	for name, value := range fields {
+		item.Fields[name] = value
+	}
+	if parent != 0 {
+		item.Fields["System.Parent"] = float64(parent)
+	}
+	c.workItems = append(c.workItems, item)
+	return item, nil
+}
+
+// WorkItemTypes returns the seeded work item types, or Agile's.
+func (c *Client) WorkItemTypes(ctx context.Context) ([]domain.WorkItemType, error) {
+	if err := c.check(ctx, "WorkItemTypes"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.types != nil {
+		return c.types, nil
+	}
+	return defaultTypes, nil
+}
+
+// WorkItemStates returns the seeded states of a type, or New, Active,
+// Resolved and Closed.
+func (c *Client) WorkItemStates(ctx context.Context, workItemType string) ([]domain.WorkItemState, error) {
+	if err := c.check(ctx, "WorkItemStates"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if states, ok := c.states[workItemType]; ok {
+		return states, nil
+	}
+	return defaultStates, nil
+}
+
+// AreaPaths returns the distinct area paths of seeded work items.
+func (c *Client) AreaPaths(ctx context.Context) ([]string, error) {
+	if err := c.check(ctx, "AreaPaths"); err != nil {
+		return nil, err
+	}
+	return c.distinctField("System.AreaPath"), nil
+}
+
+// IterationPaths returns the distinct iteration paths of seeded work items.
+func (c *Client) IterationPaths(ctx context.Context) ([]string, error) {
+	if err := c.check(ctx, "IterationPaths"); err != nil {
+		return nil, err
+	}
+	return c.distinctField("System.IterationPath"), nil
+}
+
+// WorkItemUpdates returns the seeded revisions of a work item, followed by
+// those made with UpdateWorkItem.
+func (c *Client) WorkItemUpdates(ctx context.Context, id int) ([]domain.WorkItemUpdate, error) {
+	return list(ctx, c, "WorkItemUpdates", func() []domain.WorkItemUpdate { return c.updates[id] })
+}
+
+// WorkItemComments returns the comments seeded for, or added to, a work
+// item.
+func (c *Client) WorkItemComments(ctx context.Context, id int) ([]domain.Comment, error) {
+	return list(ctx, c, "WorkItemComments", func() []domain.Comment { return c.comments[id] })
+}
+
+// AddWorkItemComment appends a comment by the authenticated user.
+func (c *Client) AddWorkItemComment(ctx context.Context, id int, text string) (domain.Comment, error) {
+	if err := c.check(ctx, "AddWorkItemComment"); err != nil {
+		return domain.Comment{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user := c.connection.AuthenticatedUser
+	comment := domain.Comment{
+		ID:          len(c.comments[id]) + 1,
+		WorkItemID:  id,
+		Version:     1,
+		Text:        text,
+		CreatedBy:   domain.Identity{ID: user.ID, DisplayName: user.ProviderDisplayName, UniqueName: user.Account()},
+		CreatedDate: time.Now(),
+	}
+	c.comments[id] = append(c.comments[id], comment)
+	return comment, nil
+}
+
+// SearchIdentities returns the seeded identities whose display or unique
+// name contains query, ignoring case.
+func (c *Client) SearchIdentities(ctx context.Context, query string) ([]domain.Identity, error) {
+	identities, err := list(ctx, c, "SearchIdentities", func() []domain.Identity { return c.identities })
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(query)
+	found := identities[:0]
+	for _, id := range identities {
+		if strings.Contains(strings.ToLower(id.DisplayName), query) || strings.Contains(strings.ToLower(id.UniqueName), query) {
+			found = append(found, id)
+		}
+	}
+	return found, nil
+}
+
+func (c *Client) distinctField(name string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[string]bool)
+	values := []string{}
+	for _, item := range c.workItems {
+		if v := item.GetField(name); v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Builds returns a pager over seeded builds matching the filters.
+func (c *Client) Builds(ctx context.Context, status, result string, pageSize int) *api.Pager[domain.Build] {
+	return pager(ctx, c, "ListBuilds", pageSize, func() []domain.Build {
+		return c.filterBuilds(status, result)
+	})
+}
+
+// ListBuilds returns at most top seeded builds matching the filters.
+func (c *Client) ListBuilds(ctx context.Context, status, result string, top int) ([]domain.Build, error) {
+	if err := c.check(ctx, "ListBuilds"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return limit(c.filterBuilds(status, result), top), nil
+}
+
+// GetFailedBuilds returns recently failed builds.
+func (c *Client) GetFailedBuilds(ctx context.Context, top int) ([]domain.Build, error) {
+	return c.ListBuilds(ctx, "completed", "failed", top)
+}
+
+// GetRunningBuilds returns in-progress builds.
+func (c *Client) GetRunningBuilds(ctx context.Context) ([]domain.Build, error) {
+	return c.ListBuilds(ctx, "inProgress", "", 50)
+}
+
+// filterBuilds must be called with c.mu held.
+func (c *Client) filterBuilds(status, result string) []domain.Build {
+	builds := []domain.Build{}
+	for _, b := range c.builds {
+		if matches(b.Status, status) && matches(b.Result, result) {
+			builds = append(builds, b)
+		}
+	}
+	return builds
+}
+
+// Pipelines returns a pager over seeded pipelines.
+func (c *Client) Pipelines(ctx context.Context) *api.Pager[domain.Pipeline] {
+	return pager(ctx, c, "ListPipelines", 0, func() []domain.Pipeline { return c.pipelines })
+}
+
+// ListPipelines returns all seeded pipelines.
+func (c *Client) ListPipelines(ctx context.Context) ([]domain.Pipeline, error) {
+	return list(ctx, c, "ListPipelines", func() []domain.Pipeline { return c.pipelines })
+}
+
+// Repositories returns a pager over seeded repositories.
+func (c *Client) Repositories(ctx context.Context) *api.Pager[domain.Repository] {
+	return pager(ctx, c, "ListRepositories", 0, func() []domain.Repository { return c.repos })
+}
+
+// ListRepositories returns all seeded repositories.
+func (c *Client) ListRepositories(ctx context.Context) ([]domain.Repository, error) {
+	return list(ctx, c, "ListRepositories", func() []domain.Repository { return c.repos })
+}
+
+// PullRequests returns a pager over seeded pull requests with the status.
+func (c *Client) PullRequests(ctx context.Context, status string, pageSize int) *api.Pager[domain.PullRequest] {
+	return pager(ctx, c, "GetActivePullRequests", pageSize, func() []domain.PullRequest {
+		return c.filterPullRequests(status)
+	})
+}
+
+// GetActivePullRequests returns at most top seeded active pull requests.
+func (c *Client) GetActivePullRequests(ctx context.Context, top int) ([]domain.PullRequest, error) {
+	if err := c.check(ctx, "GetActivePullRequests"); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return limit(c.filterPullRequests("active"), top), nil
+}
+
+// filterPullRequests must be called with c.mu held.
+func (c *Client) filterPullRequests(status string) []domain.PullRequest {
+	prs := []domain.PullRequest{}
+	for _, pr := range c.pullRequests {
+		if matches(pr.Status, status) {
+			prs = append(prs, pr)
+		}
+	}
+	return prs
+}
+
+// Projects returns a pager over seeded projects.
+func (c *Client) Projects(ctx context.Context) *api.Pager[domain.Project] {
+	return pager(ctx, c, "ListProjects", 0, func() []domain.Project { return c.projects })
+}
+
+// ListProjects returns all seeded projects.
+func (c *Client) ListProjects(ctx context.Context) ([]domain.Project, error) {
+	return list(ctx, c, "ListProjects", func() []domain.Project { return c.projects })
+}
+
+// ConnectionData returns the value set with SetConnectionData.
+func (c *Client) ConnectionData(ctx context.Context) (domain.ConnectionData, error) {
+	if err := c.check(ctx, "ConnectionData"); err != nil {
+		return domain.ConnectionData{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connection, nil
+}
+
+// ForProject returns c. The fake does not partition its data by project.
+func (c *Client) ForProject(project string) api.Service {
+	return c
+}
+
+func matches(value, filter string) bool {
+	return filter == "" || strings.EqualFold(value, filter)
+}
+
+func limit[T any](items []T, top int) []T {
+	if top > 0 && len(items) > top {
+		return items[:top]
+	}
+	return items
+}
+
+// list copies the slice returned by get under the read lock.
+func list[T any](ctx context.Context, c *Client, method string, get func() []T) ([]T, error) {
+	if err := c.check(ctx, method); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]T{}, get()...), nil
+}
+
+// pager serves the slice returned by get in $skip-style pages of pageSize,
+// or as a single page when pageSize is zero.
+func pager[T any](ctx context.Context, c *Client, method string, pageSize int, get func() []T) *api.Pager[T] {
+	return api.NewPager(pageSize, func(_ string, skip int) ([]T, string, error) {
+		items, err := list(ctx, c, method, get)
+		if err != nil {
+			return nil, "", err
+		}
+		if skip >= len(items) {
+			return nil, "", nil
+		}
+		items = items[skip:]
+		if pageSize > 0 {
+			items = limit(items, pageSize)
+		}
+		return items, "", nil
+	})
+}
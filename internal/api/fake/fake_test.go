@@ -0,0 +1,152 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/api/fake"
+	"github.com/user/apo/internal/domain"
+)
+
+func buildIDs(builds []domain.Build) []int {
+	ids := []int{}
+	for _, b := range builds {
+		ids = append(ids, b.ID)
+	}
+	return ids
+}
+
+func TestBuildsPagingAndFilters(t *testing.T) {
+	ctx := context.Background()
+	svc := fake.New()
+	svc.SeedBuilds(
+		domain.Build{ID: 1, Status: "completed", Result: "succeeded"},
+		domain.Build{ID: 2, Status: "completed", Result: "failed"},
+		domain.Build{ID: 3, Status: "inProgress"},
+		domain.Build{ID: 4, Status: "completed", Result: "failed"},
+		domain.Build{ID: 5, Status: "completed", Result: "canceled"},
+	)
+
+	tests := []struct {
+		name           string
+		status, result string
+		pageSize       int
+		wantPages      [][]int
+	}{
+		{"one page", "", "", 0, [][]int{{1, 2, 3, 4, 5}}},
+		{"pages of two", "", "", 2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{"exact pages", "", "", 5, [][]int{{1, 2, 3, 4, 5}}},
+		{"status filter", "inProgress", "", 2, [][]int{{3}}},
+		{"result filter, case-insensitive", "Completed", "FAILED", 1, [][]int{{2}, {4}}},
+		{"nothing matches", "notStarted", "", 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages [][]int
+			p := svc.Builds(ctx, tt.status, tt.result, tt.pageSize)
+			for p.Next() {
+				pages = append(pages, buildIDs(p.Page()))
+			}
+			if err := p.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.EqualFunc(pages, tt.wantPages, slices.Equal) {
+				t.Errorf("pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+
+	t.Run("top", func(t *testing.T) {
+		failed, err := svc.GetFailedBuilds(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buildIDs(failed); !slices.Equal(got, []int{2}) {
+			t.Errorf("GetFailedBuilds(1) = %v, want [2]", got)
+		}
+	})
+}
+
+func TestFail(t *testing.T) {
+	ctx := context.Background()
+	svc := fake.New()
+	svc.SeedPipelines(domain.Pipeline{ID: 1})
+	boom := &api.Error{StatusCode: 503, Message: "unavailable"}
+
+	svc.Fail("ListPipelines", boom)
+	if _, err := svc.ListPipelines(ctx); !errors.Is(err, boom) {
+		t.Errorf("ListPipelines error = %v, want %v", err, boom)
+	}
+	if p := svc.Pipelines(ctx); p.Next() || !errors.Is(p.Err(), boom) {
+		t.Errorf("Pipelines pager error = %v, want %v", p.Err(), boom)
+	}
+
+	svc.Fail("ListPipelines", nil)
+	if got, err := svc.ListPipelines(ctx); err != nil || len(got) != 1 {
+		t.Errorf("ListPipelines after clearing = %v, %v", got, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := svc.ListPipelines(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListPipelines with a canceled context = %v", err)
+	}
+}
+
+func TestMyWorkItemsSkipsClosed(t *testing.T) {
+	svc := fake.New()
+	svc.SeedWorkItems(
+		domain.WorkItem{ID: 1, Fields: map[string]interface{}{"System.State": "Active"}},
+		domain.WorkItem{ID: 2, Fields: map[string]interface{}{"System.State": "Closed"}},
+		domain.WorkItem{ID: 3, Fields: map[string]interface{}{"System.State": "Removed"}},
+		domain.WorkItem{ID: 4, Fields: map[string]interface{}{"System.State": "New"}},
+	)
+	items, err := svc.GetMyWorkItems(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if !slices.Equal(ids, []int{1, 4}) {
+		t.Errorf("GetMyWorkItems = %v, want [1 4]", ids)
+	}
+}
+
+func TestUpdateWorkItemRevisions(t *testing.T) {
+	ctx := context.Background()
+	svc := fake.New()
+	svc.SeedWorkItems(domain.WorkItem{ID: 7, Rev: 3, Fields: map[string]interface{}{"System.Title": "Old"}})
+
+	updated, err := svc.UpdateWorkItem(ctx, 7, 3, map[string]interface{}{"System.Title": "New"})
+	if err != nil {
+		t.Fatalf("update at the current revision: %v", err)
+	}
+	if updated.Rev != 4 || updated.Title() != "New" {
+		t.Errorf("updated = rev %d %q, want rev 4 %q", updated.Rev, updated.Title(), "New")
+	}
+
+	updates, err := svc.WorkItemUpdates(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].Rev != 4 || updates[0].Fields["System.Title"].Old() != "Old" {
+		t.Errorf("updates = %+v, want one recording Old → New at rev 4", updates)
+	}
+
+	_, err = svc.UpdateWorkItem(ctx, 7, 3, map[string]interface{}{"System.Title": "Stale"})
+	if !api.IsConflict(err) {
+		t.Errorf("update at a stale revision: error = %v, want a conflict", err)
+	}
+	if item, _ := svc.GetWorkItem(ctx, 7); item.Rev != 4 || item.Title() != "New" {
+		t.Errorf("after the conflict the item is rev %d %q, want it unchanged", item.Rev, item.Title())
+	}
+
+	if _, err := svc.UpdateWorkItem(ctx, 8, 1, nil); !api.IsNotFound(err) {
+		t.Errorf("update of a missing item: error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/user/apo/internal/domain"
+)
+
+// Me stands for the authenticated user wherever apo takes an identity,
+// such as an assignee.
+const Me = "@me"
+
+// ResolveMe returns name, or the authenticated user's account name when
+// name is Me.
+func ResolveMe(ctx context.Context, svc Service, name string) (string, error) {
+	if !strings.EqualFold(name, Me) {
+		return name, nil
+	}
+	conn, err := svc.ConnectionData(ctx)
+	if err != nil {
+		return "", err
+	}
+	if account := conn.AuthenticatedUser.Account(); account != "" {
+		return account, nil
+	}
+	return conn.AuthenticatedUser.ProviderDisplayName, nil
+}
+
+// SearchIdentities returns the users whose name, email or account matches
+// query, as the people picker in the web UI does.
+func (c *Client) SearchIdentities(ctx context.Context, query string) ([]domain.Identity, error) {
+	body := map[string]interface{}{
+		"query":           query,
+		"identityTypes":   []string{"user"},
+		"operationScopes": []string{"ims", "source"},
+		"properties":      []string{"DisplayName", "Mail", "SignInAddress", "SamAccountName"},
+		"options":         map[string]int{"MinResults": 5, "MaxResults": 20},
+	}
+	var resp struct {
+		Results []struct {
+			Identities []struct {
+				LocalID        string `json:"localId"`
+				DisplayName    string `json:"displayName"`
+				Mail           string `json:"mail"`
+				SignInAddress  string `json:"signInAddress"`
+				SamAccountName string `json:"samAccountName"`
+			} `json:"identities"`
+		} `json:"results"`
+	}
+	search := &call{
+		method:    "POST",
+		url:       withAPIVersion(c.orgURL("_apis/IdentityPicker/Identities"), c.apiVersion.get()+"-preview"),
+		body:      body,
+		result:    &resp,
+		retrySafe: true, // a search changes nothing
+	}
+	if _, err := c.send(ctx, search); err != nil {
+		return nil, err
+	}
+
+	var identities []domain.Identity
+	for _, result := range resp.Results {
+		for _, id := range result.Identities {
+			unique := id.SignInAddress
+			if unique == "" {
+				unique = id.Mail
+			}
+			if unique == "" {
+				unique = id.SamAccountName
+			}
+			identities = append(identities, domain.Identity{ID: id.LocalID, DisplayName: id.DisplayName, UniqueName: unique})
+		}
+	}
+	return identities, nil
+}
+
+// mentionPattern matches an @mention of a name, account or email address
+// at the start of the text or after a space.
+var mentionPattern = regexp.MustCompile(`(^|\s)@([\w.\-]+(?:@[\w\-]+(?:\.[\w\-]+)+)?)`)
+
+// CommentHTML turns comment text as typed into the HTML Azure DevOps
+// stores. Each @name, @account or @email becomes a mention of that user,
+// who is then notified; @me mentions the authenticated user. It fails if a
+// mention matches no one, or several people and none exactly.
+func CommentHTML(ctx context.Context, svc Service, text string) (string, error) {
+	var b strings.Builder
+	resolved := make(map[string]domain.Identity)
+	last := 0
+	for _, m := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		name := strings.TrimRight(text[m[4]:m[5]], ".")
+		end := m[4] + len(name)
+
+		identity, ok := resolved[strings.ToLower(name)]
+		if !ok {
+			var err error
+			if identity, err = resolveMention(ctx, svc, name); err != nil {
+				return "", err
+			}
+			resolved[strings.ToLower(name)] = identity
+		}
+		b.WriteString(html.EscapeString(text[last : m[4]-1]))
+		fmt.Fprintf(&b, `<a href="#" data-vss-mention="version:2.0,%s">@%s</a>`, identity.ID, html.EscapeString(identity.ShortName()))
+		last = end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), nil
+}
+
+// resolveMention finds the one user a mention refers to.
+func resolveMention(ctx context.Context, svc Service, name string) (domain.Identity, error) {
+	if strings.EqualFold("@"+name, Me) {
+		conn, err := svc.ConnectionData(ctx)
+		if err != nil {
+			return domain.Identity{}, err
+		}
+		user := conn.AuthenticatedUser
+		return domain.Identity{ID: user.ID, DisplayName: user.ProviderDisplayName, UniqueName: user.Account()}, nil
+	}
+
+	matches, err := svc.SearchIdentities(ctx, name)
+	if err != nil {
+		return domain.Identity{}, err
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	for _, id := range matches {
+		if strings.EqualFold(id.UniqueName, name) || strings.EqualFold(id.DisplayName, name) {
+			return id, nil
+		}
+	}
+	if len(matches) == 0 {
+		return domain.Identity{}, fmt.Errorf("no one matches @%s", name)
+	}
+	var names []string
+	for _, id := range matches[:min(len(matches), 3)] {
+		names = append(names, id.UniqueName)
+	}
+	return domain.Identity{}, fmt.Errorf("@%s could be %s; mention them by email", name, strings.Join(names, " or "))
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"strconv"
+)
+
+const (
+	// defaultPageSize is the $top used for endpoints that page with $skip.
+	defaultPageSize = 200
+
+	continuationHeader = "x-ms-continuationtoken"
+)
+
+// listResponse is the envelope Azure DevOps wraps list results in.
+type listResponse[T any] struct {
+	Count int `json:"count"`
+	Value []T `json:"value"`
+}
+
+// PageFunc fetches a single page. It returns the items and the continuation
+// token for the next page, if the server sent one.
+type PageFunc[T any] func(token string, skip int) ([]T, string, error)
+
+// Pager iterates over a paginated list endpoint one page at a time.
+//
+// Pages are followed using the x-ms-continuationtoken response header when
+// the server provides one, and by advancing $skip otherwise.
+//
+//	p := client.Pipelines(ctx)
+//	for p.Next() {
+//		for _, pl := range p.Page() { ... }
+//	}
+//	if err := p.Err(); err != nil { ... }
+type Pager[T any] struct {
+	fetch    PageFunc[T]
+	pageSize int
+	token    string
+	skip     int
+	page     []T
+	err      error
+	done     bool
+}
+
+// NewPager returns a pager that calls fetch for each page. A pageSize of
+// zero disables $skip paging, so only continuation tokens are followed.
+func NewPager[T any](pageSize int, fetch PageFunc[T]) *Pager[T] {
+	return &Pager[T]{fetch: fetch, pageSize: pageSize}
+}
+
+// Next fetches the next page. It returns false when there are no more pages
+// or an error occurred.
+func (p *Pager[T]) Next() bool {
+	if p.done {
+		return false
+	}
+
+	items, token, err := p.fetch(p.token, p.skip)
+	if err != nil {
+		p.err = err
+		p.done = true
+		return false
+	}
+	if len(items) == 0 {
+		p.done = true
+		return false
+	}
+
+	switch {
+	case token != "" && token != p.token:
+		p.token = token
+	case token == "" && p.pageSize > 0 && len(items) >= p.pageSize:
+		p.skip += len(items)
+	default:
+		p.done = true
+	}
+
+	p.page = items
+	return true
+}
+
+// Page returns the items of the current page.
+func (p *Pager[T]) Page() []T { return p.page }
+
+// Err returns the error that stopped iteration, if any.
+func (p *Pager[T]) Err() error { return p.err }
+
+// All collects items from every page, stopping once max items have been
+// read. A max of zero or less means no limit.
+func (p *Pager[T]) All(max int) ([]T, error) {
+	all := []T{}
+	for p.Next() {
+		all = append(all, p.Page()...)
+		if max > 0 && len(all) >= max {
+			return all[:max], nil
+		}
+	}
+	return all, p.Err()
+}
+
+// pagedList returns a pager over a project-scoped (or, when orgScoped is set,
+// organization-scoped) list endpoint. A pageSize of zero omits $top and
+// relies solely on continuation tokens.
+func pagedList[T any](ctx context.Context, c *Client, path string, orgScoped bool, pageSize int, params ...string) *Pager[T] {
+	return NewPager(pageSize, func(token string, skip int) ([]T, string, error) {
+		query := append([]string{}, params...)
+		if pageSize > 0 {
+			query = append(query, "$top", strconv.Itoa(pageSize))
+		}
+		if skip > 0 {
+			query = append(query, "$skip", strconv.Itoa(skip))
+		}
+		if token != "" {
+			query = append(query, "continuationToken", token)
+		}
+
+		u := c.url(path, query...)
+		if orgScoped {
+			u = c.orgURL(path, query...)
+		}
+
+		var resp listResponse[T]
+		header, err := c.send(ctx, &call{method: "GET", url: u, result: &resp})
+		if err != nil {
+			return nil, "", err
+		}
+		return resp.Value, header.Get(continuationHeader), nil
+	})
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/user/apo/internal/domain"
+)
+
+// classificationDepth is how deep area and iteration trees are fetched.
+const classificationDepth = 10
+
+// WorkItemTypes returns the work item types of the project's process that
+// can be created, with their fields.
+func (c *Client) WorkItemTypes(ctx context.Context) ([]domain.WorkItemType, error) {
+	var list domain.WorkItemTypeList
+	if err := c.do(ctx, "GET", c.url("_apis/wit/workitemtypes"), nil, &list); err != nil {
+		return nil, err
+	}
+	types := list.Value[:0]
+	for _, t := range list.Value {
+		if !t.IsDisabled {
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// WorkItemStates returns the states a work item type can be in, in the
+// order the process defines them.
+func (c *Client) WorkItemStates(ctx context.Context, workItemType string) ([]domain.WorkItemState, error) {
+	var list domain.WorkItemStateList
+	u := c.url("_apis/wit/workitemtypes/" + url.PathEscape(workItemType) + "/states")
+	if err := c.do(ctx, "GET", u, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Value, nil
+}
+
+// AreaPaths returns the project's area paths, as used in System.AreaPath,
+// in tree order.
+func (c *Client) AreaPaths(ctx context.Context) ([]string, error) {
+	return c.classificationPaths(ctx, "Areas")
+}
+
+// IterationPaths returns the project's iteration paths, as used in
+// System.IterationPath, in tree order.
+func (c *Client) IterationPaths(ctx context.Context) ([]string, error) {
+	return c.classificationPaths(ctx, "Iterations")
+}
+
+func (c *Client) classificationPaths(ctx context.Context, group string) ([]string, error) {
+	var root domain.ClassificationNode
+	u := c.url("_apis/wit/classificationnodes/"+group, "$depth", strconv.Itoa(classificationDepth))
+	if err := c.do(ctx, "GET", u, nil, &root); err != nil {
+		return nil, err
+	}
+	var paths []string
+	var walk func(n domain.ClassificationNode)
+	walk = func(n domain.ClassificationNode) {
+		paths = append(paths, fieldPath(n.Path))
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return paths, nil
+}
+
+// fieldPath converts a node path such as "\Fabrikam\Iteration\Sprint 4"
+// to the form work item fields use, "Fabrikam\Sprint 4".
+func fieldPath(nodePath string) string {
+	parts := strings.Split(strings.TrimPrefix(nodePath, `\`), `\`)
+	if len(parts) < 2 {
+		return parts[0]
+	}
+	return strings.Join(append(parts[:1], parts[2:]...), `\`)
+}
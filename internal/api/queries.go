@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/user/apo/internal/domain"
+)
+
+// queryDepth is the deepest $depth the queries endpoint accepts.
+const queryDepth = 2
+
+var guidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// QueryWorkItems runs a WIQL query in the project and returns the matching
+// work items in result order, up to the client's item cap. Tree and
+// one-hop queries return every linked item once.
+func (c *Client) QueryWorkItems(ctx context.Context, wiql string) ([]domain.WorkItem, error) {
+	var result domain.WorkItemList
+	u := c.url("_apis/wit/wiql", "$top", strconv.Itoa(c.wiqlTop()))
+	query := &call{method: "POST", url: u, body: map[string]string{"query": wiql}, result: &result, retrySafe: true}
+	if _, err := c.send(ctx, query); err != nil {
+		return nil, err
+	}
+	return c.getWorkItems(ctx, result.IDs(), workItemFields)
+}
+
+// ListQueries returns the project's saved queries, shared and personal,
+// without folders, in tree order.
+func (c *Client) ListQueries(ctx context.Context) ([]domain.Query, error) {
+	var list domain.QueryList
+	u := c.url("_apis/wit/queries", "$depth", strconv.Itoa(queryDepth), "$expand", "wiql")
+	if err := c.do(ctx, "GET", u, nil, &list); err != nil {
+		return nil, err
+	}
+	var queries []domain.Query
+	for _, q := range list.Value {
+		if err := c.collectQueries(ctx, q, &queries); err != nil {
+			return nil, err
+		}
+	}
+	return queries, nil
+}
+
+// collectQueries appends the queries in q to out, fetching folders nested
+// deeper than the endpoint returns at once.
+func (c *Client) collectQueries(ctx context.Context, q domain.Query, out *[]domain.Query) error {
+	if !q.IsFolder {
+		*out = append(*out, q)
+		return nil
+	}
+	if q.HasChildren && q.Children == nil {
+		folder, err := c.getQuery(ctx, q.ID, queryDepth)
+		if err != nil {
+			return err
+		}
+		q.Children = folder.Children
+	}
+	for _, child := range q.Children {
+		if err := c.collectQueries(ctx, child, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetQuery returns a saved query by ID or by path, such as
+// "Shared Queries/Active bugs".
+func (c *Client) GetQuery(ctx context.Context, idOrPath string) (domain.Query, error) {
+	return c.getQuery(ctx, idOrPath, 0)
+}
+
+func (c *Client) getQuery(ctx context.Context, idOrPath string, depth int) (domain.Query, error) {
+	segments := strings.Split(strings.Trim(idOrPath, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	params := []string{"$expand", "wiql"}
+	if depth > 0 {
+		params = append(params, "$depth", strconv.Itoa(depth))
+	}
+	var q domain.Query
+	err := c.do(ctx, "GET", c.url("_apis/wit/queries/"+strings.Join(segments, "/"), params...), nil, &q)
+	return q, err
+}
+
+// RunQuery runs a saved query, given by ID or path, and returns the
+// matching work items.
+func (c *Client) RunQuery(ctx context.Context, idOrPath string) ([]domain.WorkItem, error) {
+	id := idOrPath
+	if !guidPattern.MatchString(id) {
+		q, err := c.GetQuery(ctx, idOrPath)
+		if err != nil {
+			return nil, err
+		}
+		if q.IsFolder {
+			return nil, fmt.Errorf("%s is a query folder", q.Path)
+		}
+		id = q.ID
+	}
+
+	var result domain.WorkItemList
+	u := c.url("_apis/wit/wiql/"+id, "$top", strconv.Itoa(c.wiqlTop()))
+	if err := c.do(ctx, "GET", u, nil, &result); err != nil {
+		return nil, err
+	}
+	return c.getWorkItems(ctx, result.IDs(), workItemFields)
+}
+
+// wiqlTop returns the $top for WIQL queries: the item cap, within the
+// service's limit.
+func (c *Client) wiqlTop() int {
+	top := min(c.maxItems, wiqlMaxResults)
+	if top <= 0 {
+		top = wiqlMaxResults
+	}
+	return top
+}
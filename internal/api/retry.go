@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	MaxRetries    int           // retries after the first attempt; 0 disables
+	BaseDelay     time.Duration // initial backoff
+	MaxDelay      time.Duration // cap for a single computed backoff
+	MaxRetryAfter time.Duration // longest Retry-After the client will wait out
+}
+
+// DefaultRetryPolicy returns the policy used by NewClient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:    3,
+		BaseDelay:     500 * time.Millisecond,
+		MaxDelay:      10 * time.Second,
+		MaxRetryAfter: 60 * time.Second,
+	}
+}
+
+// backoff returns a fully jittered exponential delay for the given attempt
+// (starting at 0).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isIdempotent reports whether a request with this method can be safely
+// repeated after a failure whose outcome is unknown.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus reports whether a status code indicates a transient
+// failure.
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given either as seconds or as an
+// HTTP date. It returns false when the header is absent or invalid.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// RateLimit is the most recent rate-limit state reported by Azure DevOps
+// through the X-RateLimit-* headers. The service only sends these headers
+// once a caller starts being delayed or is close to its limit.
+type RateLimit struct {
+	Resource  string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	Delay     time.Duration
+}
+
+// parseRateLimit reads X-RateLimit-* headers. It returns false when none
+// are present.
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	if h.Get("X-RateLimit-Limit") == "" && h.Get("X-RateLimit-Remaining") == "" {
+		return RateLimit{}, false
+	}
+	rl := RateLimit{Resource: h.Get("X-RateLimit-Resource")}
+	rl.Limit, _ = strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	rl.Remaining, _ = strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rl.Reset = time.Unix(reset, 0)
+	}
+	if delay, err := strconv.ParseFloat(h.Get("X-RateLimit-Delay"), 64); err == nil {
+		rl.Delay = time.Duration(delay * float64(time.Second))
+	}
+	return rl, true
+}
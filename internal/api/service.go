@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+
+	"github.com/user/apo/internal/domain"
+)
+
+// Service is the set of Azure DevOps operations apo depends on. Client
+// implements it against the REST API; package fake provides an in-memory
+// implementation for tests.
+type Service interface {
+	GetMyWorkItems(ctx context.Context) ([]domain.WorkItem, error)
+	QueryWorkItems(ctx context.Context, wiql string) ([]domain.WorkItem, error)
+	ListQueries(ctx context.Context) ([]domain.Query, error)
+	GetQuery(ctx context.Context, idOrPath string) (domain.Query, error)
+	RunQuery(ctx context.Context, idOrPath string) ([]domain.WorkItem, error)
+	GetWorkItem(ctx context.Context, id int) (domain.WorkItem, error)
+	UpdateWorkItem(ctx context.Context, id, rev int, fields map[string]interface{}) (domain.WorkItem, error)
+	WorkItemUpdates(ctx context.Context, id int) ([]domain.WorkItemUpdate, error)
+	CreateWorkItem(ctx context.Context, workItemType string, fields map[string]interface{}, parent int) (domain.WorkItem, error)
+	WorkItemTypes(ctx context.Context) ([]domain.WorkItemType, error)
+	WorkItemStates(ctx context.Context, workItemType string) ([]domain.WorkItemState, error)
+	AreaPaths(ctx context.Context) ([]string, error)
+	IterationPaths(ctx context.Context) ([]string, error)
+	WorkItemComments(ctx context.Context, id int) ([]domain.Comment, error)
+	AddWorkItemComment(ctx context.Context, id int, text string) (domain.Comment, error)
+	SearchIdentities(ctx context.Context, query string) ([]domain.Identity, error)
+
+	Builds(ctx context.Context, status, result string, pageSize int) *Pager[domain.Build]
+	ListBuilds(ctx context.Context, status, result string, top int) ([]domain.Build, error)
+	GetFailedBuilds(ctx context.Context, top int) ([]domain.Build, error)
+	GetRunningBuilds(ctx context.Context) ([]domain.Build, error)
+
+	Pipelines(ctx context.Context) *Pager[domain.Pipeline]
+	ListPipelines(ctx context.Context) ([]domain.Pipeline, error)
+
+	Repositories(ctx context.Context) *Pager[domain.Repository]
+	ListRepositories(ctx context.Context) ([]domain.Repository, error)
+
+	PullRequests(ctx context.Context, status string, pageSize int) *Pager[domain.PullRequest]
+	GetActivePullRequests(ctx context.Context, top int) ([]domain.PullRequest, error)
+
+	Projects(ctx context.Context) *Pager[domain.Project]
+	ListProjects(ctx context.Context) ([]domain.Project, error)
+
+	// ConnectionData returns the identity requests are authenticated as.
+	ConnectionData(ctx context.Context) (domain.ConnectionData, error)
+
+	// ForProject returns a Service for another project in the same
+	// organization.
+	ForProject(project string) Service
+
+	// RateLimit returns the last rate-limit state the server reported.
+	RateLimit() (RateLimit, bool)
+}
+
+var _ Service = (*Client)(nil)
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// apiVersion is the REST API version requests are sent with. It starts at
+// the configured version and is lowered the first time the server reports
+// that version as out of range, as Azure DevOps Server does for versions
+// newer than its release (2019 supports 5.0, 2020 6.0, 2022 7.0).
+type apiVersion struct {
+	mu      sync.Mutex
+	version string
+}
+
+func (v *apiVersion) get() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.version
+}
+
+// lower switches to version if it is older than the current one.
+func (v *apiVersion) lower(version string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if versionLess(version, v.version) {
+		v.version = version
+	}
+}
+
+var supportedVersionPattern = regexp.MustCompile(`version this server supports is (\d+\.\d+)`)
+
+// supportedVersion extracts the latest version the server supports from a
+// VssVersionOutOfRangeException body.
+func supportedVersion(body []byte) (string, bool) {
+	var payload struct {
+		Message string `json:"message"`
+		TypeKey string `json:"typeKey"`
+	}
+	if json.Unmarshal(body, &payload) != nil || payload.TypeKey != "VssVersionOutOfRangeException" {
+		return "", false
+	}
+	m := supportedVersionPattern.FindStringSubmatch(payload.Message)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// withAPIVersion returns rawURL with its api-version query parameter set to
+// version.
+func withAPIVersion(rawURL, version string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	q.Set("api-version", version)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// previewSuffix returns the "-preview" part of rawURL's api-version, if
+// any, so that a request for an endpoint that only exists as a preview
+// still asks for one after negotiation.
+func previewSuffix(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if _, preview, ok := strings.Cut(u.Query().Get("api-version"), "-"); ok {
+		return "-" + preview
+	}
+	return ""
+}
+
+// versionLess reports whether version a ("6.0", "7.1-preview.1") is older
+// than b, comparing major and minor numbers.
+func versionLess(a, b string) bool {
+	pa, pb := versionParts(a), versionParts(b)
+	if pa[0] != pb[0] {
+		return pa[0] < pb[0]
+	}
+	return pa[1] < pb[1]
+}
+
+func versionParts(v string) [2]int {
+	v, _, _ = strings.Cut(v, "-")
+	major, minor, _ := strings.Cut(v, ".")
+	var parts [2]int
+	parts[0], _ = strconv.Atoi(major)
+	parts[1], _ = strconv.Atoi(minor)
+	return parts
+}
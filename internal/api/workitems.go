@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/user/apo/internal/domain"
+)
+
+const (
+	// workItemBatchSize is the most IDs the work items endpoint accepts.
+	workItemBatchSize = 200
+	// workItemWorkers bounds the number of concurrent batch requests.
+	workItemWorkers = 4
+	// wiqlMaxResults is the most results a WIQL query may return.
+	wiqlMaxResults = 20000
+)
+
+// workItemFields are the fields the UI and agent read from work items.
+var workItemFields = []string{
+	"System.Id",
+	"System.Title",
+	"System.State",
+	"System.WorkItemType",
+	"System.AssignedTo",
+	"System.CreatedDate",
+	"System.ChangedDate",
+	"System.Description",
+	"System.TeamProject",
+	"System.AreaPath",
+	"System.IterationPath",
+	"System.ChangedBy",
+	"Microsoft.VSTS.Common.Priority",
+}
+
+// getWorkItems fetches work items by ID in batches, preserving the order of
+// ids. IDs that no longer exist are omitted.
+func (c *Client) getWorkItems(ctx context.Context, ids []int, fields []string) ([]domain.WorkItem, error) {
+	if len(ids) == 0 {
+		return []domain.WorkItem{}, nil
+	}
+
+	var batches [][]int
+	for start := 0; start < len(ids); start += workItemBatchSize {
+		end := min(start+workItemBatchSize, len(ids))
+		batches = append(batches, ids[start:end])
+	}
+
+	// Stop the remaining batches as soon as one fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]domain.WorkItem, len(batches))
+	errs := make([]error, len(batches))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(workItemWorkers, len(batches)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = c.getWorkItemBatch(ctx, batches[i], fields)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range batches {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]domain.WorkItem, len(ids))
+	for _, batch := range results {
+		for _, item := range batch {
+			byID[item.ID] = item
+		}
+	}
+
+	items := make([]domain.WorkItem, 0, len(byID))
+	for _, id := range ids {
+		if item, ok := byID[id]; ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (c *Client) getWorkItemBatch(ctx context.Context, ids []int, fields []string) ([]domain.WorkItem, error) {
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = strconv.Itoa(id)
+	}
+
+	params := []string{"ids", strings.Join(strIDs, ","), "errorPolicy", "omit"}
+	if len(fields) > 0 {
+		params = append(params, "fields", strings.Join(fields, ","))
+	}
+
+	var batch domain.WorkItemBatch
+	if err := c.do(ctx, "GET", c.url("_apis/wit/workitems", params...), nil, &batch); err != nil {
+		return nil, err
+	}
+
+	// With errorPolicy=omit, missing items come back as null entries.
+	items := batch.Value[:0]
+	for _, item := range batch.Value {
+		if item.ID != 0 {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// GetWorkItem returns a single work item with the fields apo shows.
+func (c *Client) GetWorkItem(ctx context.Context, id int) (domain.WorkItem, error) {
+	items, err := c.getWorkItems(ctx, []int{id}, workItemFields)
+	if err != nil {
+		return domain.WorkItem{}, err
+	}
+	if len(items) == 0 {
+		return domain.WorkItem{}, &Error{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("work item %d does not exist", id)}
+	}
+	return items[0], nil
+}
+
+// WorkItemUpdates returns the revisions of a work item, oldest first, each
+// with the fields it changed.
+func (c *Client) WorkItemUpdates(ctx context.Context, id int) ([]domain.WorkItemUpdate, error) {
+	path := fmt.Sprintf("_apis/wit/workitems/%d/updates", id)
+	return pagedList[domain.WorkItemUpdate](ctx, c, path, false, defaultPageSize).All(0)
+}
+
+// UpdateWorkItem sets fields on a work item, given by reference name such
+// as "System.State", and returns the updated item. The update is rejected
+// with a conflict (see IsConflict) if the work item is no longer at rev,
+// so changes made by someone else in the meantime are never overwritten.
+func (c *Client) UpdateWorkItem(ctx context.Context, id, rev int, fields map[string]interface{}) (domain.WorkItem, error) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	patch := []domain.PatchOperation{{Op: "test", Path: "/rev", Value: rev}}
+	for _, name := range names {
+		patch = append(patch, domain.PatchOperation{Op: "add", Path: "/fields/" + name, Value: fields[name]})
+	}
+
+	var item domain.WorkItem
+	update := &call{
+		method:      "PATCH",
+		url:         c.url("_apis/wit/workitems/" + strconv.Itoa(id)),
+		body:        patch,
+		result:      &item,
+		contentType: "application/json-patch+json",
+	}
+	if _, err := c.send(ctx, update); err != nil {
+		return domain.WorkItem{}, err
+	}
+	return item, nil
+}
+
+// CreateWorkItem creates a work item of the given type with fields, given
+// by reference name, and returns it. A non-zero parent links it as a child
+// of that work item.
+func (c *Client) CreateWorkItem(ctx context.Context, workItemType string, fields map[string]interface{}, parent int) (domain.WorkItem, error) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var patch []domain.PatchOperation
+	for _, name := range names {
+		patch = append(patch, domain.PatchOperation{Op: "add", Path: "/fields/" + name, Value: fields[name]})
+	}
+	if parent != 0 {
+		patch = append(patch, domain.PatchOperation{Op: "add", Path: "/relations/-", Value: domain.WorkItemRelation{
+			Rel: "System.LinkTypes.Hierarchy-Reverse",
+			URL: c.baseURL + "/_apis/wit/workItems/" + strconv.Itoa(parent),
+		}})
+	}
+
+	var item domain.WorkItem
+	create := &call{
+		method:      "POST",
+		url:         c.url("_apis/wit/workitems/$" + url.PathEscape(workItemType)),
+		body:        patch,
+		result:      &item,
+		contentType: "application/json-patch+json",
+	}
+	if _, err := c.send(ctx, create); err != nil {
+		return domain.WorkItem{}, err
+	}
+	return item, nil
+}
+
+// firstError returns the first error that caused the batches to stop,
+// preferring it over the cancellations it triggered in the others.
+func firstError(errs []error) error {
+	var canceled error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, context.Canceled):
+			if canceled == nil {
+				canceled = err
+			}
+		default:
+			return err
+		}
+	}
+	return canceled
+}
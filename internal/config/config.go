@@ -4,31 +4,166 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/user/apo/internal/secret"
 )
 
 const (
 	DefaultAPIURL     = "https://dev.azure.com"
 	DefaultAPIVersion = "7.1"
 	DefaultTimeout    = 30 * time.Second
+	DefaultMaxItems   = 5000
+
+	// DefaultProfile names the connection stored at the top level of the
+	// config file, outside "profiles".
+	DefaultProfile = "default"
+
+	// ServerCloud and ServerOnPrem are the values of server_type: Azure
+	// DevOps Services or a self-hosted Azure DevOps Server (formerly TFS).
+	ServerCloud  = "cloud"
+	ServerOnPrem = "server"
+
+	// DefaultCollection is the collection Azure DevOps Server creates.
+	DefaultCollection = "DefaultCollection"
+
+	// AuthPAT and AuthBearer are the values of auth.mode.
+	AuthPAT    = "pat"
+	AuthBearer = "bearer"
+
+	// DefaultTokenScope requests an access token for Azure DevOps, whose
+	// Entra ID resource ID is fixed.
+	DefaultTokenScope = "499b84ac-1321-427f-aa17-267ca6975798/.default"
+
+	DefaultBuildsRefresh       = 30 * time.Second
+	DefaultPullRequestsRefresh = 60 * time.Second
+	DefaultWorkItemsRefresh    = 5 * time.Minute
 )
 
 // Config holds the application configuration.
 type Config struct {
 	Organization string `json:"organization"`
 	Project      string `json:"project"`
-	PAT          string `json:"pat"`
+	PAT          string `json:"pat,omitempty"`
+	EncryptedPAT string `json:"pat_encrypted,omitempty"`
+	PATCommand   string `json:"pat_command,omitempty"` // prints the PAT on stdout; used when PAT is empty
+	PATExpires   string `json:"pat_expires,omitempty"` // YYYY-MM-DD; apo doctor warns as it approaches
+	Auth         *Auth  `json:"auth,omitempty"`        // nil authenticates with the PAT
+	APIURL       string `json:"api_url,omitempty"`
+	APIVersion   string `json:"api_version,omitempty"` // highest version to use; lowered to what the server supports
+	ServerType   string `json:"server_type,omitempty"` // ServerCloud (default) or ServerOnPrem
+	Collection   string `json:"collection,omitempty"`  // project collection on Azure DevOps Server
+	MaxItems     int    `json:"max_items,omitempty"`
+	MaxRetries   int    `json:"max_retries,omitempty"` // negative disables retries
+	DisableCache bool   `json:"disable_cache,omitempty"`
+
+	// PATStore selects how PATs are written to the config file: empty for
+	// plaintext, or secret.KeyFile or secret.Passphrase to encrypt them.
+	PATStore string `json:"pat_store,omitempty"`
+
+	// OrgProjects lists, per organization, the projects aggregated in the
+	// TUI's org-wide mode. Organizations not listed use every project.
+	OrgProjects map[string][]string `json:"org_projects,omitempty"`
+
+	AutoRefresh AutoRefresh `json:"auto_refresh"`
+
+	ActiveProfile string             `json:"active_profile,omitempty"`
+	Profiles      map[string]Profile `json:"profiles,omitempty"`
+	LastProjects  map[string]string  `json:"last_projects,omitempty"` // "profile/organization" → project last opened in the TUI
+
+	profile string      // profile applied by Load; empty for the default connection
+	base    Profile     // default connection as read from the file
+	applied Profile     // connection as applied, with the last project and environment overrides
+	secrets *secret.Box // opens and seals encrypted PATs
+
+	// envNoCache and envNoAutoRefresh are set when APO_NO_CACHE or
+	// APO_NO_AUTO_REFRESH, rather than the file, turned the setting on, so
+	// Save leaves them out of the file.
+	envNoCache       bool
+	envNoAutoRefresh bool
+}
+
+// Profile is a named connection to an organization and project.
+type Profile struct {
+	Organization string `json:"organization"`
+	Project      string `json:"project"`
+	PAT          string `json:"pat,omitempty"`
+	EncryptedPAT string `json:"pat_encrypted,omitempty"`
+	PATCommand   string `json:"pat_command,omitempty"`
+	PATExpires   string `json:"pat_expires,omitempty"`
+	Auth         *Auth  `json:"auth,omitempty"`
 	APIURL       string `json:"api_url,omitempty"`
 	APIVersion   string `json:"api_version,omitempty"`
+	ServerType   string `json:"server_type,omitempty"`
+	Collection   string `json:"collection,omitempty"`
 }
 
-// Load reads configuration from file and environment variables.
+// Auth selects how apo authenticates. With Mode AuthBearer it uses OAuth
+// access tokens, such as Entra ID tokens for a service principal, from the
+// client credentials grant or from TokenCommand.
+type Auth struct {
+	Mode         string `json:"mode,omitempty"`      // AuthPAT (default) or AuthBearer
+	TenantID     string `json:"tenant_id,omitempty"` // Entra ID tenant; sets the default TokenURL
+	TokenURL     string `json:"token_url,omitempty"` // OAuth 2.0 token endpoint
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"` // or AZURE_DEVOPS_CLIENT_SECRET
+	Scope        string `json:"scope,omitempty"`         // defaults to DefaultTokenScope
+	TokenCommand string `json:"token_command,omitempty"` // prints an access token, e.g. az account get-access-token
+}
+
+// AutoRefresh configures how often the TUI polls for changes in the
+// background. Zero intervals use the defaults.
+type AutoRefresh struct {
+	Disabled            bool `json:"disabled,omitempty"`
+	BuildsSeconds       int  `json:"builds_seconds,omitempty"`
+	PullRequestsSeconds int  `json:"pull_requests_seconds,omitempty"`
+	WorkItemsSeconds    int  `json:"work_items_seconds,omitempty"`
+}
+
+// Builds returns the build polling interval.
+func (r AutoRefresh) Builds() time.Duration {
+	return interval(r.BuildsSeconds, DefaultBuildsRefresh)
+}
+
+// PullRequests returns the pull request polling interval.
+func (r AutoRefresh) PullRequests() time.Duration {
+	return interval(r.PullRequestsSeconds, DefaultPullRequestsRefresh)
+}
+
+// WorkItems returns the work item polling interval.
+func (r AutoRefresh) WorkItems() time.Duration {
+	return interval(r.WorkItemsSeconds, DefaultWorkItemsRefresh)
+}
+
+func interval(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// PromptPassphrase, when set, is called to ask for the passphrase that
+// encrypts PATs if APO_PASSPHRASE is not set.
+var PromptPassphrase func() (string, error)
+
+// Load reads configuration from file and environment variables, using the
+// profile named by AZURE_DEVOPS_PROFILE or, failing that, the active profile.
 func Load() (*Config, error) {
+	return LoadProfile("")
+}
+
+// LoadProfile is like Load but uses the named profile when name is not
+// empty.
+func LoadProfile(name string) (*Config, error) {
 	cfg := &Config{
 		APIURL:     DefaultAPIURL,
 		APIVersion: DefaultAPIVersion,
+		MaxItems:   DefaultMaxItems,
 	}
 
 	configPath := GetConfigPath()
@@ -37,6 +172,21 @@ func Load() (*Config, error) {
 			return nil, fmt.Errorf("parsing config file: %w", err)
 		}
 	}
+	if err := cfg.openPATs(); err != nil {
+		return nil, err
+	}
+	cfg.base = cfg.Connection()
+
+	if name == "" {
+		name = os.Getenv("AZURE_DEVOPS_PROFILE")
+	}
+	if name == "" {
+		name = cfg.ActiveProfile
+	}
+	if err := cfg.UseProfile(name); err != nil {
+		return nil, err
+	}
+	cfg.useLastProject()
 
 	// Environment overrides
 	if org := os.Getenv("AZURE_DEVOPS_ORG"); org != "" {
@@ -54,19 +204,384 @@ func Load() (*Config, error) {
 	if version := os.Getenv("AZURE_DEVOPS_API_VERSION"); version != "" {
 		cfg.APIVersion = version
 	}
-
-	if cfg.APIURL == "" {
-		cfg.APIURL = DefaultAPIURL
+	if serverType := os.Getenv("AZURE_DEVOPS_SERVER_TYPE"); serverType != "" {
+		cfg.ServerType = serverType
+	}
+	if collection := os.Getenv("AZURE_DEVOPS_COLLECTION"); collection != "" {
+		cfg.Collection = collection
 	}
-	if cfg.APIVersion == "" {
-		cfg.APIVersion = DefaultAPIVersion
+	if os.Getenv("APO_NO_CACHE") != "" && !cfg.DisableCache {
+		cfg.DisableCache, cfg.envNoCache = true, true
 	}
+	if os.Getenv("APO_NO_AUTO_REFRESH") != "" && !cfg.AutoRefresh.Disabled {
+		cfg.AutoRefresh.Disabled, cfg.envNoAutoRefresh = true, true
+	}
+
+	cfg.applyDefaults()
+	if cfg.MaxItems <= 0 {
+		cfg.MaxItems = DefaultMaxItems
+	}
+	cfg.applied = cfg.Connection()
 
 	return cfg, nil
 }
 
-// Save writes the configuration to the config file.
+func (c *Config) applyDefaults() {
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+	if c.APIVersion == "" {
+		c.APIVersion = DefaultAPIVersion
+	}
+}
+
+// Connection returns the connection currently in use.
+func (c *Config) Connection() Profile {
+	return Profile{
+		Organization: c.Organization,
+		Project:      c.Project,
+		PAT:          c.PAT,
+		PATCommand:   c.PATCommand,
+		PATExpires:   c.PATExpires,
+		Auth:         c.Auth,
+		APIURL:       c.APIURL,
+		APIVersion:   c.APIVersion,
+		ServerType:   c.ServerType,
+		Collection:   c.Collection,
+	}
+}
+
+func (c *Config) setConnection(p Profile) {
+	c.Organization = p.Organization
+	c.Project = p.Project
+	c.PAT = p.PAT
+	c.PATCommand = p.PATCommand
+	c.PATExpires = p.PATExpires
+	c.Auth = p.Auth
+	c.APIURL = p.APIURL
+	c.APIVersion = p.APIVersion
+	c.ServerType = p.ServerType
+	c.Collection = p.Collection
+}
+
+// IsOnPrem reports whether the connection is to Azure DevOps Server.
+func (c *Config) IsOnPrem() bool {
+	return strings.EqualFold(c.ServerType, ServerOnPrem)
+}
+
+// CollectionName returns the first path segment under the server URL: the
+// organization on Azure DevOps Services, the collection on Azure DevOps
+// Server.
+func (c *Config) CollectionName() string {
+	if !c.IsOnPrem() {
+		return c.Organization
+	}
+	if c.Collection != "" {
+		return c.Collection
+	}
+	if c.Organization != "" {
+		return c.Organization
+	}
+	return DefaultCollection
+}
+
+// OrganizationURL returns the URL of the organization or collection, such
+// as https://dev.azure.com/contoso or https://tfs.corp/DefaultCollection.
+// REST and web URLs are both built on it.
+func (c *Config) OrganizationURL() string {
+	return strings.TrimRight(c.APIURL, "/") + "/" + url.PathEscape(c.CollectionName())
+}
+
+// Authentication returns the auth settings in use, with defaults filled in
+// and AZURE_DEVOPS_CLIENT_SECRET applied.
+func (c *Config) Authentication() Auth {
+	var auth Auth
+	if c.Auth != nil {
+		auth = *c.Auth
+	}
+	if auth.Mode == "" {
+		auth.Mode = AuthPAT
+	}
+	if auth.TokenURL == "" && auth.TenantID != "" {
+		auth.TokenURL = "https://login.microsoftonline.com/" + auth.TenantID + "/oauth2/v2.0/token"
+	}
+	if auth.Scope == "" {
+		auth.Scope = DefaultTokenScope
+	}
+	if secret := os.Getenv("AZURE_DEVOPS_CLIENT_SECRET"); secret != "" {
+		auth.ClientSecret = secret
+	}
+	return auth
+}
+
+// AggregateProjects returns the projects configured for org-wide mode in
+// the current organization, or nil for all of them.
+func (c *Config) AggregateProjects() []string {
+	for org, projects := range c.OrgProjects {
+		if strings.EqualFold(org, c.CollectionName()) {
+			return projects
+		}
+	}
+	return nil
+}
+
+// ProfileName returns the name of the profile in use.
+func (c *Config) ProfileName() string {
+	if c.profile == "" {
+		return DefaultProfile
+	}
+	return c.profile
+}
+
+// ProfileNames returns all profile names, sorted, starting with
+// DefaultProfile.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// Profile returns the named connection.
+func (c *Config) Profile(name string) (Profile, bool) {
+	switch {
+	case name == "" || name == DefaultProfile:
+		return c.base, true
+	case name == c.profile:
+		return c.Connection(), true
+	}
+	p, ok := c.Profiles[name]
+	return p, ok
+}
+
+// UseProfile switches the connection fields to the named profile. Changes
+// made to them are saved back to that profile.
+func (c *Config) UseProfile(name string) error {
+	if name == c.ProfileName() || (name == "" && c.profile == "") {
+		return nil
+	}
+	p, ok := c.Profile(name)
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	c.syncProfile()
+	c.setConnection(p)
+	c.applyDefaults()
+	c.profile = name
+	if name == DefaultProfile {
+		c.profile = ""
+	}
+	c.useLastProject()
+	c.applied = c.Connection()
+	return nil
+}
+
+// AddProfile creates an empty profile.
+func (c *Config) AddProfile(name string) error {
+	if name == "" || name == DefaultProfile {
+		return fmt.Errorf("profile name %q is reserved", name)
+	}
+	if _, ok := c.Profiles[name]; ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Profile)
+	}
+	c.Profiles[name] = Profile{}
+	return nil
+}
+
+// RemoveProfile deletes a profile. If it is the active or current profile,
+// the default connection takes its place.
+func (c *Config) RemoveProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %s profile cannot be removed", DefaultProfile)
+	}
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	if c.profile == name {
+		c.UseProfile(DefaultProfile)
+	}
+	if c.ActiveProfile == name {
+		c.ActiveProfile = ""
+	}
+	delete(c.Profiles, name)
+	return nil
+}
+
+// SetActiveProfile makes the named profile the one used when none is
+// given on the command line or in AZURE_DEVOPS_PROFILE.
+func (c *Config) SetActiveProfile(name string) error {
+	if _, ok := c.Profile(name); !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	c.ActiveProfile = name
+	if name == DefaultProfile {
+		c.ActiveProfile = ""
+	}
+	return nil
+}
+
+// syncProfile records the connection fields changed since the profile was
+// applied in the profile they came from. Fields still holding the last
+// project or an environment override are left as the profile has them.
+func (c *Config) syncProfile() {
+	if c.profile == "" {
+		c.base = changed(c.base, c.applied, c.Connection())
+	} else {
+		c.Profiles[c.profile] = changed(c.Profiles[c.profile], c.applied, c.Connection())
+	}
+	c.applied = c.Connection()
+}
+
+// changed returns p with the fields that differ between was and now set
+// to their value in now.
+func changed(p, was, now Profile) Profile {
+	set := func(field *string, was, now string) {
+		if was != now {
+			*field = now
+		}
+	}
+	set(&p.Organization, was.Organization, now.Organization)
+	set(&p.Project, was.Project, now.Project)
+	set(&p.PAT, was.PAT, now.PAT)
+	set(&p.PATCommand, was.PATCommand, now.PATCommand)
+	set(&p.PATExpires, was.PATExpires, now.PATExpires)
+	set(&p.APIURL, was.APIURL, now.APIURL)
+	set(&p.APIVersion, was.APIVersion, now.APIVersion)
+	set(&p.ServerType, was.ServerType, now.ServerType)
+	set(&p.Collection, was.Collection, now.Collection)
+	if was.Auth != now.Auth {
+		p.Auth = now.Auth
+	}
+	return p
+}
+
+// Save writes the configuration to the config file. Only what was changed
+// since Load is written to the profile: the TUI's last project and
+// environment overrides stay out of it.
 func (c *Config) Save() error {
+	c.syncProfile()
+	out := *c
+	out.setConnection(c.base)
+	if c.envNoCache {
+		out.DisableCache = false
+	}
+	if c.envNoAutoRefresh {
+		out.AutoRefresh.Disabled = false
+	}
+	return write(&out)
+}
+
+// RememberProject records the current project as the last one opened in
+// the current profile's organization, so the next Load starts there. Only
+// this setting is written to the config file, so environment overrides and
+// unsaved edits stay out of it.
+func (c *Config) RememberProject() error {
+	key := c.lastProjectKey()
+	if c.LastProjects == nil {
+		c.LastProjects = make(map[string]string)
+	}
+	c.LastProjects[key] = c.Project
+
+	var onDisk Config
+	if data, err := os.ReadFile(GetConfigPath()); err == nil {
+		if err := json.Unmarshal(data, &onDisk); err != nil {
+			return fmt.Errorf("parsing config file: %w", err)
+		}
+	}
+	if onDisk.LastProjects == nil {
+		onDisk.LastProjects = make(map[string]string)
+	}
+	onDisk.LastProjects[key] = c.Project
+	return write(&onDisk)
+}
+
+// useLastProject switches to the project last opened in the profile's
+// organization, if any.
+func (c *Config) useLastProject() {
+	if project := c.LastProjects[c.lastProjectKey()]; project != "" {
+		c.Project = project
+	}
+}
+
+func (c *Config) lastProjectKey() string {
+	return c.ProfileName() + "/" + strings.ToLower(c.CollectionName())
+}
+
+// openPATs decrypts every encrypted PAT. PATs are kept in plaintext in
+// memory and encrypted again by write.
+func (c *Config) openPATs() error {
+	if c.EncryptedPAT != "" {
+		pat, err := c.box().Open(c.EncryptedPAT)
+		if err != nil {
+			return err
+		}
+		c.PAT, c.EncryptedPAT = pat, ""
+	}
+	for name, p := range c.Profiles {
+		if p.EncryptedPAT == "" {
+			continue
+		}
+		pat, err := c.box().Open(p.EncryptedPAT)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		p.PAT, p.EncryptedPAT = pat, ""
+		c.Profiles[name] = p
+	}
+	return nil
+}
+
+// sealPATs encrypts every plaintext PAT according to PATStore. Profiles is
+// replaced with a copy so the caller's map keeps its plaintext PATs.
+func (c *Config) sealPATs() error {
+	if c.PATStore == "" {
+		return nil
+	}
+	if c.PAT != "" {
+		sealed, err := c.box().Seal(c.PATStore, c.PAT)
+		if err != nil {
+			return err
+		}
+		c.PAT, c.EncryptedPAT = "", sealed
+	}
+	profiles := make(map[string]Profile, len(c.Profiles))
+	for name, p := range c.Profiles {
+		if p.PAT != "" {
+			sealed, err := c.box().Seal(c.PATStore, p.PAT)
+			if err != nil {
+				return fmt.Errorf("profile %s: %w", name, err)
+			}
+			p.PAT, p.EncryptedPAT = "", sealed
+		}
+		profiles[name] = p
+	}
+	if c.Profiles != nil {
+		c.Profiles = profiles
+	}
+	return nil
+}
+
+// SetPassphrase sets the passphrase PATs are encrypted with from now on.
+func (c *Config) SetPassphrase(passphrase string) {
+	c.box().SetPassphrase(passphrase)
+}
+
+func (c *Config) box() *secret.Box {
+	if c.secrets == nil {
+		c.secrets = secret.New(GetKeyPath(), PromptPassphrase)
+	}
+	return c.secrets
+}
+
+func write(c *Config) error {
+	if err := c.sealPATs(); err != nil {
+		return fmt.Errorf("encrypting PAT: %w", err)
+	}
 	configPath := GetConfigPath()
 	dir := filepath.Dir(configPath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
@@ -83,11 +598,28 @@ func (c *Config) Save() error {
 
 // Validate checks that required configuration is present.
 func (c *Config) Validate() error {
-	if c.Organization == "" {
+	switch {
+	case c.IsOnPrem():
+		if c.APIURL == "" || c.APIURL == DefaultAPIURL {
+			return fmt.Errorf("api_url must point at your Azure DevOps Server, e.g. https://tfs.corp")
+		}
+	case c.ServerType != "" && !strings.EqualFold(c.ServerType, ServerCloud):
+		return fmt.Errorf("unknown server_type %q (want %s or %s)", c.ServerType, ServerCloud, ServerOnPrem)
+	case c.Organization == "":
 		return fmt.Errorf("organization is required")
 	}
-	if c.PAT == "" {
-		return fmt.Errorf("personal access token (PAT) is required")
+	auth := c.Authentication()
+	switch auth.Mode {
+	case AuthPAT:
+		if c.PAT == "" && c.PATCommand == "" {
+			return fmt.Errorf("personal access token (PAT) or pat_command is required")
+		}
+	case AuthBearer:
+		if auth.TokenCommand == "" && (auth.TokenURL == "" || auth.ClientID == "" || auth.ClientSecret == "") {
+			return fmt.Errorf("bearer auth requires token_command, or tenant_id (or token_url), client_id and client_secret")
+		}
+	default:
+		return fmt.Errorf("unknown auth mode %q (want %s or %s)", auth.Mode, AuthPAT, AuthBearer)
 	}
 	return nil
 }
@@ -111,3 +643,19 @@ func GetConfigPath() string {
 	}
 	return filepath.Join(home, ".config", "apo", "config.json")
 }
+
+// GetKeyPath returns the path to the machine key that encrypts PATs when
+// pat_store is "keyfile".
+func GetKeyPath() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), "key")
+}
+
+// GetCacheDir returns the directory holding cached API responses.
+func GetCacheDir() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), "cache")
+}
+
+// GetSnapshotDir returns the directory holding offline data snapshots.
+func GetSnapshotDir() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), "snapshots")
+}
@@ -14,6 +14,7 @@ type Build struct {
 	Definition   BuildDefinition `json:"definition"`
 	RequestedBy  Identity        `json:"requestedBy"`
 	SourceBranch string          `json:"sourceBranch"`
+	Project      Project         `json:"project"`
 	URL          string          `json:"url"`
 }
 
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Comment is a comment in a work item's discussion.
+type Comment struct {
+	ID           int       `json:"id"`
+	WorkItemID   int       `json:"workItemId"`
+	Version      int       `json:"version"`
+	Text         string    `json:"text"` // HTML
+	CreatedBy    Identity  `json:"createdBy"`
+	CreatedDate  time.Time `json:"createdDate"`
+	ModifiedDate time.Time `json:"modifiedDate"`
+	IsDeleted    bool      `json:"isDeleted"`
+}
+
+// CommentList is a page of a work item's comments.
+type CommentList struct {
+	TotalCount        int       `json:"totalCount"`
+	Count             int       `json:"count"`
+	Comments          []Comment `json:"comments"`
+	ContinuationToken string    `json:"continuationToken"`
+}
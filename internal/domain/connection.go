@@ -0,0 +1,35 @@
+package domain
+
+// anonymousID is the identity Azure DevOps reports for unauthenticated
+// requests.
+const anonymousID = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+
+// ConnectionData describes the server and the identity a request was
+// authenticated as.
+type ConnectionData struct {
+	AuthenticatedUser ConnectionUser `json:"authenticatedUser"`
+	InstanceID        string         `json:"instanceId"`
+	DeploymentType    string         `json:"deploymentType"` // "hosted" or "onPremises"
+}
+
+// ConnectionUser is the authenticated identity in ConnectionData.
+type ConnectionUser struct {
+	ID                  string                   `json:"id"`
+	ProviderDisplayName string                   `json:"providerDisplayName"`
+	Properties          map[string]PropertyValue `json:"properties"`
+}
+
+// PropertyValue is a typed identity property.
+type PropertyValue struct {
+	Value string `json:"$value"`
+}
+
+// Account returns the user's account name, usually an email address.
+func (u ConnectionUser) Account() string {
+	return u.Properties["Account"].Value
+}
+
+// IsAnonymous reports whether the request was not authenticated.
+func (u ConnectionUser) IsAnonymous() bool {
+	return u.ID == "" || u.ID == anonymousID
+}
@@ -0,0 +1,64 @@
+package domain
+
+// WorkItemState is a state a work item type can be in.
+type WorkItemState struct {
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Category string `json:"category"` // e.g. "Proposed", "InProgress", "Completed"
+}
+
+// WorkItemStateList is the response from listing a type's states.
+type WorkItemStateList struct {
+	Count int             `json:"count"`
+	Value []WorkItemState `json:"value"`
+}
+
+// ClassificationNode is an area or iteration in a project's tree.
+type ClassificationNode struct {
+	ID            int                  `json:"id"`
+	Name          string               `json:"name"`
+	StructureType string               `json:"structureType"` // "area" or "iteration"
+	Path          string               `json:"path"`          // e.g. "\\Fabrikam\\Iteration\\Sprint 4"
+	HasChildren   bool                 `json:"hasChildren"`
+	Children      []ClassificationNode `json:"children"`
+}
+
+// WorkItemType is a type of work item in a project's process, such as Bug
+// or Task.
+type WorkItemType struct {
+	Name          string              `json:"name"`
+	ReferenceName string              `json:"referenceName"`
+	Description   string              `json:"description"`
+	IsDisabled    bool                `json:"isDisabled"`
+	Fields        []WorkItemTypeField `json:"fields"`
+}
+
+// WorkItemTypeField is a field of a work item type.
+type WorkItemTypeField struct {
+	ReferenceName  string      `json:"referenceName"`
+	Name           string      `json:"name"`
+	AlwaysRequired bool        `json:"alwaysRequired"`
+	DefaultValue   interface{} `json:"defaultValue"`
+}
+
+// WorkItemTypeList is the response from listing work item types.
+type WorkItemTypeList struct {
+	Count int            `json:"count"`
+	Value []WorkItemType `json:"value"`
+}
+
+// MissingFields returns the names of the fields a new work item of this
+// type must have but that values, keyed by reference name, leaves empty
+// and the process does not default.
+func (t WorkItemType) MissingFields(values map[string]interface{}) []string {
+	var missing []string
+	for _, f := range t.Fields {
+		if !f.AlwaysRequired || (f.DefaultValue != nil && f.DefaultValue != "") {
+			continue
+		}
+		if v, ok := values[f.ReferenceName]; !ok || v == nil || v == "" {
+			missing = append(missing, f.Name)
+		}
+	}
+	return missing
+}
@@ -39,8 +39,9 @@ func (pr *PullRequest) IsActive() bool {
 
 // RepoRef is a reference to a repository in a PR.
 type RepoRef struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Project Project `json:"project"`
 }
 
 // Reviewer represents a PR reviewer.
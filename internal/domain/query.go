@@ -0,0 +1,28 @@
+package domain
+
+// Query is a saved work item query or a folder of them.
+type Query struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Path        string  `json:"path"` // e.g. "Shared Queries/Triage/Active bugs"
+	IsFolder    bool    `json:"isFolder"`
+	IsPublic    bool    `json:"isPublic"` // shared rather than personal
+	HasChildren bool    `json:"hasChildren"`
+	QueryType   string  `json:"queryType"` // "flat", "tree" or "oneHop"
+	Wiql        string  `json:"wiql"`
+	Children    []Query `json:"children"`
+}
+
+// QueryList is the response from listing queries.
+type QueryList struct {
+	Count int     `json:"count"`
+	Value []Query `json:"value"`
+}
+
+// WorkItemLink is a link between two work items in the result of a tree
+// or one-hop query. Source is nil for top-level items.
+type WorkItemLink struct {
+	Rel    string       `json:"rel"`
+	Source *WorkItemRef `json:"source"`
+	Target *WorkItemRef `json:"target"`
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// WorkItemUpdate is one revision of a work item: who made it, when, and
+// the fields and links it changed.
+type WorkItemUpdate struct {
+	ID          int                    `json:"id"`
+	WorkItemID  int                    `json:"workItemId"`
+	Rev         int                    `json:"rev"`
+	RevisedBy   Identity               `json:"revisedBy"`
+	RevisedDate time.Time              `json:"revisedDate"` // when the revision was superseded
+	Fields      map[string]FieldChange `json:"fields"`
+	Relations   *RelationChanges       `json:"relations"`
+}
+
+// FieldChange is the value of a field before and after a WorkItemUpdate.
+// OldValue is nil when the field was set for the first time.
+type FieldChange struct {
+	OldValue interface{} `json:"oldValue"`
+	NewValue interface{} `json:"newValue"`
+}
+
+// Old returns the previous value as a string.
+func (c FieldChange) Old() string { return fieldString(c.OldValue) }
+
+// New returns the new value as a string.
+func (c FieldChange) New() string { return fieldString(c.NewValue) }
+
+// RelationChanges are the links a WorkItemUpdate added and removed.
+type RelationChanges struct {
+	Added   []WorkItemRelation `json:"added"`
+	Removed []WorkItemRelation `json:"removed"`
+	Updated []WorkItemRelation `json:"updated"`
+}
+
+// WorkItemUpdateList is the response from listing a work item's updates.
+type WorkItemUpdateList struct {
+	Count int              `json:"count"`
+	Value []WorkItemUpdate `json:"value"`
+}
+
+// bookkeepingFields change with every revision, or follow from another
+// field, and say nothing a reader of the history wants to know.
+var bookkeepingFields = map[string]bool{
+	"System.Id":             true,
+	"System.Rev":            true,
+	"System.ChangedDate":    true,
+	"System.ChangedBy":      true,
+	"System.RevisedDate":    true,
+	"System.AuthorizedDate": true,
+	"System.AuthorizedAs":   true,
+	"System.PersonId":       true,
+	"System.Watermark":      true,
+	"System.AreaId":         true,
+	"System.IterationId":    true,
+	"System.NodeName":       true,
+	"System.CommentCount":   true,
+	"System.CreatedDate":    true,
+	"System.CreatedBy":      true,
+}
+
+// leadingFields are listed first among changed fields, in this order, as
+// they are what a reader of the history looks for.
+var leadingFields = []string{"System.State", "System.Reason", "System.AssignedTo", "System.Title"}
+
+// ChangedFields returns the reference names of the fields the update
+// changed: state, reason, assignee and title first, the rest sorted, and
+// a comment made with the change (System.History) last. Fields every
+// revision changes, such as System.ChangedDate, are left out.
+func (u WorkItemUpdate) ChangedFields() []string {
+	var names []string
+	for name := range u.Fields {
+		if !bookkeepingFields[name] && !strings.HasPrefix(name, "System.AreaLevel") && !strings.HasPrefix(name, "System.IterationLevel") {
+			names = append(names, name)
+		}
+	}
+	rank := func(name string) int {
+		for i, lead := range leadingFields {
+			if name == lead {
+				return i
+			}
+		}
+		if name == "System.History" {
+			return len(leadingFields) + 1
+		}
+		return len(leadingFields)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if ri, rj := rank(names[i]), rank(names[j]); ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// ChangedDate returns when the change was made. RevisedDate is when the
+// next change superseded it, so System.ChangedDate is preferred.
+func (u WorkItemUpdate) ChangedDate() time.Time {
+	if change, ok := u.Fields["System.ChangedDate"]; ok {
+		if t, err := time.Parse(time.RFC3339, change.New()); err == nil {
+			return t
+		}
+	}
+	return u.RevisedDate
+}
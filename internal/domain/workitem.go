@@ -1,5 +1,7 @@
 package domain
 
+import "strconv"
+
 // WorkItem represents an Azure DevOps work item.
 type WorkItem struct {
 	ID     int                    `json:"id"`
@@ -13,15 +15,21 @@ func (w *WorkItem) GetField(name string) string {
 	if w.Fields == nil {
 		return ""
 	}
-	if val, ok := w.Fields[name]; ok {
-		switch v := val.(type) {
-		case string:
-			return v
-		case map[string]interface{}:
-			if displayName, ok := v["displayName"].(string); ok {
-				return displayName
-			}
+	return fieldString(w.Fields[name])
+}
+
+// fieldString formats a field value as a string, using the display name of
+// an identity.
+func fieldString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if displayName, ok := v["displayName"].(string); ok {
+			return displayName
 		}
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
 	}
 	return ""
 }
@@ -41,20 +49,85 @@ func (w *WorkItem) Type() string {
 	return w.GetField("System.WorkItemType")
 }
 
+// TeamProject returns the name of the project the work item belongs to.
+func (w *WorkItem) TeamProject() string {
+	return w.GetField("System.TeamProject")
+}
+
 // AssignedTo returns the assigned user.
 func (w *WorkItem) AssignedTo() string {
 	return w.GetField("System.AssignedTo")
 }
 
+// AreaPath returns the work item's area, e.g. "Fabrikam\Web".
+func (w *WorkItem) AreaPath() string {
+	return w.GetField("System.AreaPath")
+}
+
+// IterationPath returns the work item's iteration, e.g. "Fabrikam\Sprint 4".
+func (w *WorkItem) IterationPath() string {
+	return w.GetField("System.IterationPath")
+}
+
+// Priority returns the work item's priority, 1 (highest) to 4, or "".
+func (w *WorkItem) Priority() string {
+	return w.GetField("Microsoft.VSTS.Common.Priority")
+}
+
+// ChangedBy returns who last changed the work item.
+func (w *WorkItem) ChangedBy() string {
+	return w.GetField("System.ChangedBy")
+}
+
+// PatchOperation is one JSON Patch (RFC 6902) operation in a work item
+// update, such as {"op": "add", "path": "/fields/System.State", "value": "Active"}.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// WorkItemRelation is a link from a work item to another work item or
+// artifact. Rel names the link type, such as
+// "System.LinkTypes.Hierarchy-Reverse" for the parent.
+type WorkItemRelation struct {
+	Rel string `json:"rel"`
+	URL string `json:"url"`
+}
+
 // WorkItemRef is a reference to a work item.
 type WorkItemRef struct {
 	ID  int    `json:"id"`
 	URL string `json:"url"`
 }
 
-// WorkItemList is the response from a WIQL query.
+// WorkItemList is the response from a WIQL query. Flat queries return
+// WorkItems; tree and one-hop queries return WorkItemRelations.
 type WorkItemList struct {
-	WorkItems []WorkItemRef `json:"workItems"`
+	QueryType         string         `json:"queryType"`
+	WorkItems         []WorkItemRef  `json:"workItems"`
+	WorkItemRelations []WorkItemLink `json:"workItemRelations"`
+}
+
+// IDs returns the IDs of the work items in the result, in order and
+// without duplicates.
+func (l WorkItemList) IDs() []int {
+	seen := make(map[int]bool)
+	var ids []int
+	add := func(ref *WorkItemRef) {
+		if ref != nil && !seen[ref.ID] {
+			seen[ref.ID] = true
+			ids = append(ids, ref.ID)
+		}
+	}
+	for i := range l.WorkItems {
+		add(&l.WorkItems[i])
+	}
+	for _, link := range l.WorkItemRelations {
+		add(link.Source)
+		add(link.Target)
+	}
+	return ids
 }
 
 // WorkItemBatch is the response from batch fetching.
@@ -0,0 +1,1201 @@
+// Package mockserver serves a fake Azure DevOps REST API from fixture JSON.
+//
+// It implements the routes api.Client calls, enforces Basic or bearer auth
+// (issuing bearer tokens from a fake OAuth token endpoint) and can inject
+// latency, throttling and small page sizes, so the TUI can be demoed and
+// integration-tested offline by pointing AZURE_DEVOPS_URL at it.
+package mockserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//go:embed fixtures/*.json
+var embedded embed.FS
+
+// Options configures a Server.
+type Options struct {
+	Organization  string        // organization served; defaults to "demo"
+	PAT           string        // token required in Basic auth; empty disables auth
+	ClientID      string        // client accepted by the token endpoint; empty disables bearer auth
+	ClientSecret  string        // secret required with ClientID
+	TokenTTL      time.Duration // lifetime of issued bearer tokens; defaults to an hour
+	Latency       time.Duration // delay added to every response
+	ThrottleEvery int           // answer every Nth request with 429; 0 disables
+	PageSize      int           // max items per page; 0 uses the client's $top
+	MaxAPIVersion string        // newest api-version accepted, like Azure DevOps Server; empty accepts all
+	DenyAreas     []string      // areas ("work", "build", "code", "project") answered as if the PAT lacked their scope
+	FixturesDir   string        // directory overriding the embedded fixtures
+}
+
+// Server is an http.Handler emulating Azure DevOps.
+type Server struct {
+	opts     Options
+	mux      *http.ServeMux
+	requests atomic.Int64
+
+	mu sync.Mutex
+	// fixtures holds the items of each fixture file. Updates replace a
+	// file's slice and the changed item rather than modifying them, so
+	// slices returned by items stay valid.
+	fixtures map[string][]map[string]interface{}
+	tokens   map[string]time.Time // issued bearer token → expiry
+}
+
+// fixtureNames are the fixture files loaded, without the .json extension.
+var fixtureNames = []string{"workitems", "builds", "pipelines", "repositories", "pullrequests", "projects"}
+
+// optionalFixtureNames are fixture files that may be missing from
+// Options.FixturesDir; a missing file serves no items.
+var optionalFixtureNames = []string{"queries", "comments", "updates"}
+
+// New creates a server, loading fixtures from opts.FixturesDir when set and
+// from the embedded defaults otherwise.
+func New(opts Options) (*Server, error) {
+	if opts.Organization == "" {
+		opts.Organization = "demo"
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = time.Hour
+	}
+
+	var fsys fs.FS
+	if opts.FixturesDir != "" {
+		fsys = os.DirFS(opts.FixturesDir)
+	} else {
+		sub, err := fs.Sub(embedded, "fixtures")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	s := &Server{
+		opts:     opts,
+		fixtures: make(map[string][]map[string]interface{}),
+		tokens:   make(map[string]time.Time),
+	}
+	for _, name := range append(fixtureNames, optionalFixtureNames...) {
+		data, err := fs.ReadFile(fsys, name+".json")
+		if errors.Is(err, fs.ErrNotExist) && slices.Contains(optionalFixtureNames, name) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading fixture %s: %w", name, err)
+		}
+		var items []map[string]interface{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("parsing fixture %s: %w", name, err)
+		}
+		s.fixtures[name] = items
+	}
+
+	s.routes()
+	return s, nil
+}
+
+func (s *Server) routes() {
+	org := "/" + s.opts.Organization
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /{tenant}/oauth2/v2.0/token", s.handleToken)
+	s.mux.HandleFunc("GET "+org+"/_apis/connectionData", s.handleConnectionData)
+	s.mux.HandleFunc("GET "+org+"/_apis/projects", s.handleProjects)
+	s.mux.HandleFunc("POST "+org+"/_apis/IdentityPicker/Identities", s.handleIdentityPicker)
+	s.mux.HandleFunc("POST "+org+"/{project}/_apis/wit/wiql", s.handleWIQL)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/wiql/{id}", s.handleSavedWIQL)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/queries", s.handleQueries)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/queries/{query...}", s.handleQuery)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/workitems", s.handleWorkItems)
+	s.mux.HandleFunc("PATCH "+org+"/{project}/_apis/wit/workitems/{id}", s.handleUpdateWorkItem)
+	s.mux.HandleFunc("POST "+org+"/{project}/_apis/wit/workitems/{type}", s.handleCreateWorkItem)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/workitems/{id}/comments", s.handleComments)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/workitems/{id}/updates", s.handleUpdates)
+	s.mux.HandleFunc("POST "+org+"/{project}/_apis/wit/workitems/{id}/comments", s.handleAddComment)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/workitemtypes", s.handleWorkItemTypes)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/workitemtypes/{type}/states", s.handleStates)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/wit/classificationnodes/{group}", s.handleClassificationNodes)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/build/builds", s.handleBuilds)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/pipelines", s.handlePipelines)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/git/repositories", s.handleRepositories)
+	s.mux.HandleFunc("GET "+org+"/{project}/_apis/git/pullrequests", s.handlePullRequests)
+}
+
+// ServeHTTP applies auth, latency and throttling, then routes the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := s.requests.Add(1)
+
+	if s.opts.Latency > 0 {
+		select {
+		case <-time.After(s.opts.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if !s.authorized(r) && !strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token") {
+		writeError(w, http.StatusUnauthorized, "UnauthorizedRequestException",
+			"TF400813: The user is not authorized to access this resource.")
+		return
+	}
+
+	// The credential is valid, so a missing scope is an authorization
+	// failure naming the authenticated identity, not an authentication one.
+	if s.denied(r.URL.Path) {
+		writeError(w, http.StatusForbidden, "UnauthorizedRequestException",
+			fmt.Sprintf("TF400813: The user '%s' is not authorized to access this resource.", mockUserID))
+		return
+	}
+
+	if requested := r.URL.Query().Get("api-version"); requested != "" && s.opts.MaxAPIVersion != "" &&
+		versionNumber(requested) > versionNumber(s.opts.MaxAPIVersion) {
+		writeError(w, http.StatusBadRequest, "VssVersionOutOfRangeException", fmt.Sprintf(
+			"The requested REST API version of %s is out of range for this server. The latest REST API version this server supports is %s.",
+			requested, s.opts.MaxAPIVersion))
+		return
+	}
+
+	if s.opts.ThrottleEvery > 0 && n%int64(s.opts.ThrottleEvery) == 0 {
+		w.Header().Set("Retry-After", "1")
+		w.Header().Set("X-RateLimit-Resource", "Core")
+		w.Header().Set("X-RateLimit-Limit", "200")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10))
+		writeError(w, http.StatusTooManyRequests, "RequestThrottledException",
+			"Request was blocked due to exceeding usage of resource 'Core'.")
+		return
+	}
+
+	w.Header().Set("ActivityId", fmt.Sprintf("00000000-0000-0000-0000-%012d", n))
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.opts.PAT == "" && s.opts.ClientID == "" {
+		return true
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		expires, ok := s.tokens[token]
+		return ok && time.Now().Before(expires)
+	}
+	_, pass, ok := r.BasicAuth()
+	return ok && s.opts.PAT != "" && pass == s.opts.PAT
+}
+
+// denied reports whether path belongs to an area listed in DenyAreas.
+func (s *Server) denied(path string) bool {
+	var area string
+	switch {
+	case strings.Contains(path, "/_apis/wit/"):
+		area = "work"
+	case strings.Contains(path, "/_apis/build/"), strings.Contains(path, "/_apis/pipelines"):
+		area = "build"
+	case strings.Contains(path, "/_apis/git/"):
+		area = "code"
+	case strings.HasSuffix(path, "/_apis/projects"):
+		area = "project"
+	}
+	for _, a := range s.opts.DenyAreas {
+		if area != "" && strings.EqualFold(a, area) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleToken emulates the Entra ID token endpoint for the client
+// credentials grant, issuing random tokens that expire after TokenTTL.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	writeOAuthError := func(status int, code, description string) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("grant_type") != "client_credentials" {
+		writeOAuthError(http.StatusBadRequest, "unsupported_grant_type", "AADSTS70003: Only client_credentials is supported.")
+		return
+	}
+	if s.opts.ClientID == "" || r.PostForm.Get("client_id") != s.opts.ClientID || r.PostForm.Get("client_secret") != s.opts.ClientSecret {
+		writeOAuthError(http.StatusUnauthorized, "invalid_client", "AADSTS7000215: Invalid client secret provided.")
+		return
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := "mock-" + hex.EncodeToString(buf)
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(s.opts.TokenTTL)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"token_type":   "Bearer",
+		"access_token": token,
+		"expires_in":   int(s.opts.TokenTTL.Seconds()),
+	})
+}
+
+// project validates the {project} path segment, writing a 404 if unknown.
+func (s *Server) project(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("project")
+	for _, p := range s.items("projects") {
+		if strings.EqualFold(str(p, "name"), name) || str(p, "id") == name {
+			return str(p, "name"), true
+		}
+	}
+	writeError(w, http.StatusNotFound, "ProjectDoesNotExistWithNameException",
+		fmt.Sprintf("TF200016: The following project does not exist: %s.", name))
+	return "", false
+}
+
+func (s *Server) items(name string) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fixtures[name]
+}
+
+// mockUserID is the identity requests are authenticated as.
+const mockUserID = "6b8e3c47-1a1e-4c8e-9c52-3f1d2b0a9e11"
+
+// mockUser returns the identity reference of the authenticated user, as
+// stored in identity fields.
+func mockUser() map[string]interface{} {
+	return map[string]interface{}{"id": mockUserID, "displayName": "Mock User", "uniqueName": "mock.user@example.com"}
+}
+
+func (s *Server) handleConnectionData(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"authenticatedUser": map[string]interface{}{
+			"id":                  mockUserID,
+			"providerDisplayName": "Mock User",
+			"properties": map[string]interface{}{
+				"Account": map[string]string{"$type": "System.String", "$value": "mock.user@example.com"},
+			},
+		},
+		"instanceId":     "4f3a6a1e-0000-4000-8000-000000000000",
+		"deploymentType": "hosted",
+	})
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	s.writePage(w, r, s.items("projects"))
+}
+
+func (s *Server) handleWIQL(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.project(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Query string `json:"query"`
+	}
+	data, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(data, &body); err != nil || body.Query == "" {
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException", "A WIQL query is required.")
+		return
+	}
+
+	s.writeWIQLResult(w, r, project, body.Query)
+}
+
+// handleSavedWIQL runs a saved query by ID.
+func (s *Server) handleSavedWIQL(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.project(w, r)
+	if !ok {
+		return
+	}
+	q := findQuery(s.items("queries"), r.PathValue("id"))
+	if q == nil || q["isFolder"] == true {
+		writeError(w, http.StatusNotFound, "QueryItemNotFoundException",
+			fmt.Sprintf("TF401243: The query %s does not exist, or you do not have permission to read it.", r.PathValue("id")))
+		return
+	}
+	s.writeWIQLResult(w, r, project, str(q, "wiql"))
+}
+
+// writeWIQLResult answers a flat query with references to the project's
+// work items that match its WHERE clause, in fixture order. A query that
+// names projects in [System.TeamProject] searches every project.
+func (s *Server) writeWIQLResult(w http.ResponseWriter, r *http.Request, project, query string) {
+	filter := parseWIQL(query)
+	items := s.projectItems("workitems", project)
+	if filter.namesProjects() {
+		items = s.items("workitems")
+	}
+	refs := []map[string]interface{}{}
+	for _, item := range items {
+		if filter.matches(item, project) {
+			refs = append(refs, map[string]interface{}{"id": item["id"], "url": item["url"]})
+		}
+	}
+	if top, err := strconv.Atoi(r.URL.Query().Get("$top")); err == nil && top < len(refs) {
+		refs = refs[:top]
+	}
+	writeJSON(w, map[string]interface{}{"queryType": "flat", "workItems": refs})
+}
+
+// handleQueries lists the root query folders, with children to $depth.
+func (s *Server) handleQueries(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.project(w, r); !ok {
+		return
+	}
+	depth := queryDepth(r)
+	roots := []map[string]interface{}{}
+	for _, q := range s.items("queries") {
+		roots = append(roots, trimQuery(q, depth, r))
+	}
+	writeJSON(w, map[string]interface{}{"count": len(roots), "value": roots})
+}
+
+// handleQuery returns one query or folder by ID or path.
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.project(w, r); !ok {
+		return
+	}
+	q := findQuery(s.items("queries"), r.PathValue("query"))
+	if q == nil {
+		writeError(w, http.StatusNotFound, "QueryItemNotFoundException",
+			fmt.Sprintf("TF401243: The query %s does not exist, or you do not have permission to read it.", r.PathValue("query")))
+		return
+	}
+	writeJSON(w, trimQuery(q, queryDepth(r), r))
+}
+
+// queryDepth returns the request's $depth, which the service caps at 2.
+func queryDepth(r *http.Request) int {
+	depth, _ := strconv.Atoi(r.URL.Query().Get("$depth"))
+	return min(max(depth, 0), 2)
+}
+
+// findQuery searches the query tree for an ID or a path.
+func findQuery(queries []map[string]interface{}, idOrPath string) map[string]interface{} {
+	idOrPath = strings.Trim(idOrPath, "/")
+	for _, q := range queries {
+		if strings.EqualFold(str(q, "id"), idOrPath) || strings.EqualFold(str(q, "path"), idOrPath) {
+			return q
+		}
+		if found := findQuery(queryChildren(q), idOrPath); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func queryChildren(q map[string]interface{}) []map[string]interface{} {
+	raw, _ := q["children"].([]interface{})
+	children := make([]map[string]interface{}, 0, len(raw))
+	for _, c := range raw {
+		if child, ok := c.(map[string]interface{}); ok {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// trimQuery copies a query, keeping children depth levels deep and the
+// WIQL only when $expand asks for it.
+func trimQuery(q map[string]interface{}, depth int, r *http.Request) map[string]interface{} {
+	out := make(map[string]interface{}, len(q))
+	for k, v := range q {
+		out[k] = v
+	}
+	if !strings.EqualFold(r.URL.Query().Get("$expand"), "wiql") && !strings.EqualFold(r.URL.Query().Get("$expand"), "all") {
+		delete(out, "wiql")
+	}
+	delete(out, "children")
+	if depth > 0 && q["isFolder"] == true {
+		var children []map[string]interface{}
+		for _, child := range queryChildren(q) {
+			children = append(children, trimQuery(child, depth-1, r))
+		}
+		if children != nil {
+			out["children"] = children
+		}
+	}
+	return out
+}
+
+func (s *Server) handleWorkItems(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.project(w, r); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	ids := strings.Split(q.Get("ids"), ",")
+	if q.Get("ids") == "" || len(ids) > 200 {
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException",
+			"The ids parameter must contain between 1 and 200 IDs.")
+		return
+	}
+
+	var fields []string
+	if f := q.Get("fields"); f != "" {
+		fields = strings.Split(f, ",")
+	}
+
+	byID := make(map[string]map[string]interface{})
+	for _, item := range s.items("workitems") {
+		byID[fmt.Sprint(item["id"])] = item
+	}
+
+	values := []interface{}{}
+	for _, id := range ids {
+		item, ok := byID[strings.TrimSpace(id)]
+		if !ok {
+			if q.Get("errorPolicy") == "omit" {
+				values = append(values, nil)
+				continue
+			}
+			writeError(w, http.StatusNotFound, "WorkItemUnauthorizedAccessException",
+				fmt.Sprintf("TF401232: Work item %s does not exist.", id))
+			return
+		}
+		values = append(values, selectFields(item, fields))
+	}
+	writeJSON(w, map[string]interface{}{"count": len(values), "value": values})
+}
+
+// handleUpdateWorkItem applies a JSON Patch to a work item. A failed test
+// of /rev is answered like the service answers a concurrent edit.
+func (s *Server) handleUpdateWorkItem(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.project(w, r); !ok {
+		return
+	}
+	var patch []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	data, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json-patch+json") {
+		writeError(w, http.StatusUnsupportedMediaType, "VssRequestContentTypeNotSupportedException",
+			"The request indicated a Content-Type of \""+r.Header.Get("Content-Type")+"\" for method type \"PATCH\" which is not supported. Valid content types for this method are: application/json-patch+json.")
+		return
+	}
+	if err := json.Unmarshal(data, &patch); err != nil || len(patch) == 0 {
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException", "A JSON Patch document is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := s.fixtures["workitems"]
+	index := -1
+	for i, item := range items {
+		if fmt.Sprint(item["id"]) == r.PathValue("id") {
+			index = i
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "WorkItemUnauthorizedAccessException",
+			fmt.Sprintf("TF401232: Work item %s does not exist, or you do not have permissions to read it.", r.PathValue("id")))
+		return
+	}
+
+	item := items[index]
+	rev, _ := item["rev"].(float64)
+	fields := make(map[string]interface{})
+	if current, ok := item["fields"].(map[string]interface{}); ok {
+		for k, v := range current {
+			fields[k] = v
+		}
+	}
+	for _, op := range patch {
+		name, isField := strings.CutPrefix(op.Path, "/fields/")
+		switch {
+		case op.Op == "test" && op.Path == "/rev":
+			if want, _ := op.Value.(float64); want != rev {
+				writeError(w, http.StatusBadRequest, "TestPatchOperationFailedException", fmt.Sprintf(
+					"Test operation failed for path '/rev': the value %d does not match the expected value %d.", int(rev), int(want)))
+				return
+			}
+		case (op.Op == "add" || op.Op == "replace") && isField:
+			if name == "System.State" && !validState(fieldStr(item, "System.WorkItemType"), fmt.Sprint(op.Value)) {
+				writeError(w, http.StatusBadRequest, "WorkItemFieldInvalidException", fmt.Sprintf(
+					"TF401320: Rule Error for field State. Error code: Required, HasValues, LimitedToValues, AllowsOldValue, InvalidEmpty. The value %q is not allowed.", op.Value))
+				return
+			}
+			if name == "System.AssignedTo" {
+				if v, ok := op.Value.(string); ok && v != "" {
+					fields[name] = map[string]interface{}{"displayName": v, "uniqueName": v}
+				} else {
+					delete(fields, name)
+				}
+				continue
+			}
+			fields[name] = op.Value
+		case op.Op == "remove" && isField:
+			delete(fields, name)
+		default:
+			writeError(w, http.StatusBadRequest, "InvalidArgumentValueException",
+				fmt.Sprintf("The operation %q on %q is not supported.", op.Op, op.Path))
+			return
+		}
+	}
+	fields["System.ChangedDate"] = time.Now().UTC().Format(time.RFC3339)
+	fields["System.ChangedBy"] = mockUser()
+
+	updated := make(map[string]interface{}, len(item))
+	for k, v := range item {
+		updated[k] = v
+	}
+	updated["rev"] = rev + 1
+	updated["fields"] = fields
+	replaced := append([]map[string]interface{}{}, items...)
+	replaced[index] = updated
+	s.fixtures["workitems"] = replaced
+	old, _ := item["fields"].(map[string]interface{})
+	s.recordUpdate(updated, old, nil)
+	writeJSON(w, updated)
+}
+
+// handleCreateWorkItem creates a work item from a JSON Patch of fields and
+// relations, requiring a title as every process does.
+func (s *Server) handleCreateWorkItem(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.project(w, r)
+	if !ok {
+		return
+	}
+	workItemType, ok := strings.CutPrefix(r.PathValue("type"), "$")
+	if _, known := mockStates[workItemType]; !ok || !known {
+		writeError(w, http.StatusNotFound, "WorkItemTypeNotFoundException",
+			fmt.Sprintf("TF201036: Work item type %s does not exist.", strings.TrimPrefix(r.PathValue("type"), "$")))
+		return
+	}
+	var patch []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	data, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(data, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException", "A JSON Patch document is required.")
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	me := mockUser()
+	fields := map[string]interface{}{
+		"System.WorkItemType":            workItemType,
+		"System.State":                   "New",
+		"System.TeamProject":             project,
+		"System.AreaPath":                project,
+		"System.IterationPath":           project,
+		"System.CreatedDate":             now,
+		"System.ChangedDate":             now,
+		"System.CreatedBy":               me,
+		"System.ChangedBy":               me,
+		"Microsoft.VSTS.Common.Priority": 2,
+	}
+	var relations []interface{}
+	for _, op := range patch {
+		if name, ok := strings.CutPrefix(op.Path, "/fields/"); ok && op.Op == "add" {
+			if v, ok := op.Value.(string); ok && name == "System.AssignedTo" && v != "" {
+				fields[name] = map[string]interface{}{"displayName": v, "uniqueName": v}
+				continue
+			}
+			fields[name] = op.Value
+			continue
+		}
+		if op.Op == "add" && op.Path == "/relations/-" {
+			relation, _ := op.Value.(map[string]interface{})
+			if str(relation, "rel") == "System.LinkTypes.Hierarchy-Reverse" {
+				parent := str(relation, "url")
+				id, _ := strconv.Atoi(parent[strings.LastIndex(parent, "/")+1:])
+				fields["System.Parent"] = id
+			}
+			relations = append(relations, relation)
+			continue
+		}
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException",
+			fmt.Sprintf("The operation %q on %q is not supported.", op.Op, op.Path))
+		return
+	}
+	if title, _ := fields["System.Title"].(string); strings.TrimSpace(title) == "" {
+		writeError(w, http.StatusBadRequest, "WorkItemFieldInvalidException",
+			"TF401320: Rule Error for field Title. Error code: Required, InvalidEmpty.")
+		return
+	}
+	if !validState(workItemType, fmt.Sprint(fields["System.State"])) {
+		writeError(w, http.StatusBadRequest, "WorkItemFieldInvalidException", fmt.Sprintf(
+			"TF401320: Rule Error for field State. Error code: LimitedToValues. The value %q is not allowed.", fields["System.State"]))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := 1
+	for _, item := range s.fixtures["workitems"] {
+		if n, ok := item["id"].(float64); ok {
+			id = max(id, int(n)+1)
+		}
+	}
+	fields["System.Id"] = id
+	item := map[string]interface{}{
+		"id":     float64(id),
+		"rev":    float64(1),
+		"fields": fields,
+		"url":    fmt.Sprintf("https://mock/%s/_apis/wit/workItems/%d", project, id),
+	}
+	if relations != nil {
+		item["relations"] = relations
+	}
+	s.fixtures["workitems"] = append(append([]map[string]interface{}{}, s.fixtures["workitems"]...), item)
+	s.recordUpdate(item, nil, relations)
+	writeJSON(w, item)
+}
+
+// handleUpdates lists a work item's revisions from the updates fixture and
+// those made through the server, oldest first.
+func (s *Server) handleUpdates(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.workItem(w, r); !ok {
+		return
+	}
+	var updates []map[string]interface{}
+	for _, u := range s.items("updates") {
+		if fmt.Sprint(u["workItemId"]) == r.PathValue("id") {
+			updates = append(updates, u)
+		}
+	}
+	s.writePage(w, r, updates)
+}
+
+// recordUpdate adds the revision that changed a work item's fields from
+// old (nil for a new item) to those of item, and marks the previous
+// revision as superseded. s.mu must be held.
+func (s *Server) recordUpdate(item map[string]interface{}, old map[string]interface{}, added []interface{}) {
+	fields, _ := item["fields"].(map[string]interface{})
+	changes := make(map[string]interface{})
+	for name, value := range fields {
+		if prev, ok := old[name]; !ok || fmt.Sprint(prev) != fmt.Sprint(value) {
+			change := map[string]interface{}{"newValue": value}
+			if ok {
+				change["oldValue"] = prev
+			}
+			changes[name] = change
+		}
+	}
+	for name, prev := range old {
+		if _, ok := fields[name]; !ok {
+			changes[name] = map[string]interface{}{"oldValue": prev}
+		}
+	}
+	changes["System.Rev"] = map[string]interface{}{"newValue": item["rev"]}
+	if rev, _ := item["rev"].(float64); rev > 1 {
+		changes["System.Rev"].(map[string]interface{})["oldValue"] = rev - 1
+	}
+
+	updates := make([]map[string]interface{}, 0, len(s.fixtures["updates"])+1)
+	id := 1
+	for _, u := range s.fixtures["updates"] {
+		if n, ok := u["id"].(float64); ok && fmt.Sprint(u["workItemId"]) == fmt.Sprint(item["id"]) {
+			id = max(id, int(n)+1)
+			if str(u, "revisedDate") == openRevision {
+				superseded := make(map[string]interface{}, len(u))
+				for k, v := range u {
+					superseded[k] = v
+				}
+				superseded["revisedDate"] = fields["System.ChangedDate"]
+				u = superseded
+			}
+		}
+		updates = append(updates, u)
+	}
+	update := map[string]interface{}{
+		"id":          float64(id),
+		"workItemId":  item["id"],
+		"rev":         item["rev"],
+		"revisedBy":   mockUser(),
+		"revisedDate": openRevision,
+		"fields":      changes,
+	}
+	if added != nil {
+		update["relations"] = map[string]interface{}{"added": added}
+	}
+	s.fixtures["updates"] = append(updates, update)
+}
+
+// openRevision is the revisedDate of a work item's latest revision.
+const openRevision = "9999-01-01T00:00:00Z"
+
+// handleComments lists a work item's comments from the comments fixture,
+// newest first unless order=asc, in pages of $top or Options.PageSize
+// chained by a continuationToken in the body.
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.workItem(w, r); !ok {
+		return
+	}
+	var comments []map[string]interface{}
+	for _, c := range s.items("comments") {
+		if fmt.Sprint(c["workItemId"]) == r.PathValue("id") {
+			comments = append(comments, c)
+		}
+	}
+	q := r.URL.Query()
+	slices.SortStableFunc(comments, func(a, b map[string]interface{}) int {
+		if q.Get("order") == "asc" {
+			return strings.Compare(str(a, "createdDate"), str(b, "createdDate"))
+		}
+		return strings.Compare(str(b, "createdDate"), str(a, "createdDate"))
+	})
+
+	start, _ := strconv.Atoi(q.Get("continuationToken"))
+	start = min(max(start, 0), len(comments))
+	size := len(comments) - start
+	if top, err := strconv.Atoi(q.Get("$top")); err == nil && top > 0 {
+		size = min(size, top)
+	}
+	if s.opts.PageSize > 0 {
+		size = min(size, s.opts.PageSize)
+	}
+	page := append([]map[string]interface{}{}, comments[start:start+size]...)
+	resp := map[string]interface{}{"totalCount": len(comments), "count": len(page), "comments": page}
+	if next := start + size; next < len(comments) && size > 0 {
+		resp["continuationToken"] = strconv.Itoa(next)
+	}
+	writeCacheableJSON(w, r, resp)
+}
+
+// handleAddComment adds a comment by the authenticated user to the
+// comments fixture.
+func (s *Server) handleAddComment(w http.ResponseWriter, r *http.Request) {
+	item, ok := s.workItem(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Text string `json:"text"`
+	}
+	data, _ := io.ReadAll(r.Body)
+	if json.Unmarshal(data, &body) != nil || strings.TrimSpace(body.Text) == "" {
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException", "The comment text cannot be empty.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := 1
+	for _, c := range s.fixtures["comments"] {
+		if n, ok := c["id"].(float64); ok {
+			id = max(id, int(n)+1)
+		}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	comment := map[string]interface{}{
+		"workItemId":   item["id"],
+		"id":           float64(id),
+		"version":      float64(1),
+		"text":         body.Text,
+		"createdBy":    mockUser(),
+		"createdDate":  now,
+		"modifiedBy":   mockUser(),
+		"modifiedDate": now,
+	}
+	s.fixtures["comments"] = append(append([]map[string]interface{}{}, s.fixtures["comments"]...), comment)
+	writeJSON(w, comment)
+}
+
+// handleIdentityPicker searches the people named in work item and comment
+// fixtures, plus the authenticated user, by display name and email.
+func (s *Server) handleIdentityPicker(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query string `json:"query"`
+	}
+	data, _ := io.ReadAll(r.Body)
+	if json.Unmarshal(data, &body) != nil {
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException", "A query is required.")
+		return
+	}
+
+	people := []map[string]interface{}{mockUser()}
+	for _, item := range s.items("workitems") {
+		fields, _ := item["fields"].(map[string]interface{})
+		for _, name := range []string{"System.AssignedTo", "System.CreatedBy", "System.ChangedBy"} {
+			if identity, ok := fields[name].(map[string]interface{}); ok {
+				people = append(people, identity)
+			}
+		}
+	}
+	for _, c := range s.items("comments") {
+		if identity, ok := c["createdBy"].(map[string]interface{}); ok {
+			people = append(people, identity)
+		}
+	}
+
+	query := strings.ToLower(body.Query)
+	seen := make(map[string]bool)
+	identities := []map[string]interface{}{}
+	for _, p := range people {
+		unique := str(p, "uniqueName")
+		if seen[unique] || str(p, "id") == "" {
+			continue
+		}
+		if strings.Contains(strings.ToLower(str(p, "displayName")), query) || strings.Contains(strings.ToLower(unique), query) {
+			seen[unique] = true
+			identities = append(identities, map[string]interface{}{
+				"entityType":    "User",
+				"localId":       str(p, "id"),
+				"displayName":   str(p, "displayName"),
+				"mail":          unique,
+				"signInAddress": unique,
+			})
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"results": []map[string]interface{}{{"queryToken": body.Query, "identities": identities}},
+	})
+}
+
+// workItem finds the work item named by the {id} path segment, writing a
+// 404 if there is none.
+func (s *Server) workItem(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	if _, ok := s.project(w, r); !ok {
+		return nil, false
+	}
+	for _, item := range s.items("workitems") {
+		if fmt.Sprint(item["id"]) == r.PathValue("id") {
+			return item, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "WorkItemUnauthorizedAccessException",
+		fmt.Sprintf("TF401232: Work item %s does not exist, or you do not have permissions to read it.", r.PathValue("id")))
+	return nil, false
+}
+
+// handleWorkItemTypes lists the types in mockStates, each requiring only a
+// title.
+func (s *Server) handleWorkItemTypes(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.project(w, r); !ok {
+		return
+	}
+	names := make([]string, 0, len(mockStates))
+	for name := range mockStates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	types := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		types[i] = map[string]interface{}{
+			"name":          name,
+			"referenceName": "Microsoft.VSTS.WorkItemTypes." + strings.ReplaceAll(name, " ", ""),
+			"isDisabled":    false,
+			"fields": []map[string]interface{}{
+				{"referenceName": "System.Title", "name": "Title", "alwaysRequired": true},
+				{"referenceName": "System.State", "name": "State", "alwaysRequired": true, "defaultValue": "New"},
+				{"referenceName": "System.AssignedTo", "name": "Assigned To", "alwaysRequired": false},
+				{"referenceName": "System.Description", "name": "Description", "alwaysRequired": false},
+				{"referenceName": "System.AreaPath", "name": "Area Path", "alwaysRequired": false},
+				{"referenceName": "System.IterationPath", "name": "Iteration Path", "alwaysRequired": false},
+				{"referenceName": "Microsoft.VSTS.Common.Priority", "name": "Priority", "alwaysRequired": false, "defaultValue": 2},
+			},
+		}
+	}
+	writeCacheableJSON(w, r, map[string]interface{}{"count": len(types), "value": types})
+}
+
+// mockStates are the states of each work item type, as in the Agile process.
+var mockStates = map[string][]string{
+	"Bug":        {"New", "Active", "Resolved", "Closed"},
+	"Task":       {"New", "Active", "Closed", "Removed"},
+	"User Story": {"New", "Active", "Resolved", "Closed", "Removed"},
+	"Feature":    {"New", "Active", "Resolved", "Closed", "Removed"},
+	"Epic":       {"New", "Active", "Resolved", "Closed", "Removed"},
+}
+
+// stateCategories are the categories of the states in mockStates.
+var stateCategories = map[string]string{
+	"New": "Proposed", "Active": "InProgress", "Resolved": "Resolved", "Closed": "Completed", "Removed": "Removed",
+}
+
+func validState(workItemType, state string) bool {
+	for _, s := range mockStates[workItemType] {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleStates(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.project(w, r); !ok {
+		return
+	}
+	names, ok := mockStates[r.PathValue("type")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "WorkItemTypeNotFoundException",
+			fmt.Sprintf("TF201036: Work item type %s does not exist.", r.PathValue("type")))
+		return
+	}
+	states := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		states[i] = map[string]interface{}{"name": name, "color": "b2b2b2", "category": stateCategories[name]}
+	}
+	writeJSON(w, map[string]interface{}{"count": len(states), "value": states})
+}
+
+// handleClassificationNodes serves the area or iteration tree of a
+// project, built from the paths its work items use.
+func (s *Server) handleClassificationNodes(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.project(w, r)
+	if !ok {
+		return
+	}
+	var field, structure, segment string
+	switch strings.ToLower(r.PathValue("group")) {
+	case "areas":
+		field, structure, segment = "System.AreaPath", "area", "Area"
+	case "iterations":
+		field, structure, segment = "System.IterationPath", "iteration", "Iteration"
+	default:
+		writeError(w, http.StatusBadRequest, "InvalidArgumentValueException", "The structure group must be Areas or Iterations.")
+		return
+	}
+
+	root := map[string]interface{}{
+		"id": 1, "name": project, "structureType": structure,
+		"path": `\` + project + `\` + segment, "hasChildren": false,
+	}
+	nextID := 2
+	for _, item := range s.projectItems("workitems", project) {
+		parts := strings.Split(fieldStr(item, field), `\`)
+		node := root
+		for i := 1; i < len(parts); i++ {
+			node = childNode(node, parts[i], structure, &nextID)
+			node["path"] = `\` + project + `\` + segment + `\` + strings.Join(parts[1:i+1], `\`)
+		}
+	}
+	writeJSON(w, root)
+}
+
+// childNode returns the child of node with the given name, adding it first
+// if needed.
+func childNode(node map[string]interface{}, name, structure string, nextID *int) map[string]interface{} {
+	children, _ := node["children"].([]interface{})
+	for _, c := range children {
+		if child := c.(map[string]interface{}); str(child, "name") == name {
+			return child
+		}
+	}
+	child := map[string]interface{}{"id": *nextID, "name": name, "structureType": structure, "hasChildren": false}
+	*nextID++
+	node["children"] = append(children, child)
+	node["hasChildren"] = true
+	return child
+}
+
+func (s *Server) handleBuilds(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.project(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	builds := []map[string]interface{}{}
+	for _, b := range s.projectItems("builds", project) {
+		if match(b, "status", q.Get("statusFilter")) && match(b, "result", q.Get("resultFilter")) {
+			builds = append(builds, b)
+		}
+	}
+	s.writePage(w, r, builds)
+}
+
+func (s *Server) handlePipelines(w http.ResponseWriter, r *http.Request) {
+	if project, ok := s.project(w, r); ok {
+		s.writePage(w, r, s.projectItems("pipelines", project))
+	}
+}
+
+func (s *Server) handleRepositories(w http.ResponseWriter, r *http.Request) {
+	if project, ok := s.project(w, r); ok {
+		s.writePage(w, r, s.projectItems("repositories", project))
+	}
+}
+
+func (s *Server) handlePullRequests(w http.ResponseWriter, r *http.Request) {
+	project, ok := s.project(w, r)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("searchCriteria.status")
+	prs := []map[string]interface{}{}
+	for _, pr := range s.projectItems("pullrequests", project) {
+		if status == "all" || match(pr, "status", status) {
+			prs = append(prs, pr)
+		}
+	}
+	s.writePage(w, r, prs)
+}
+
+// projectItems returns fixture items belonging to project. Items without a
+// recognizable project belong to every project.
+func (s *Server) projectItems(name, project string) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, item := range s.items(name) {
+		owner := itemProject(item)
+		if owner == "" || strings.EqualFold(owner, project) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func itemProject(item map[string]interface{}) string {
+	if p := fieldStr(item, "System.TeamProject"); p != "" {
+		return p
+	}
+	if p, ok := item["project"].(map[string]interface{}); ok {
+		return str(p, "name")
+	}
+	if repo, ok := item["repository"].(map[string]interface{}); ok {
+		if p, ok := repo["project"].(map[string]interface{}); ok {
+			return str(p, "name")
+		}
+	}
+	return ""
+}
+
+// writePage writes one page of items. It honors $top, $skip and
+// continuationToken (an offset), caps pages at Options.PageSize, and sets
+// x-ms-continuationtoken when more items remain.
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, items []map[string]interface{}) {
+	q := r.URL.Query()
+	start, _ := strconv.Atoi(q.Get("$skip"))
+	if token := q.Get("continuationToken"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	start = min(max(start, 0), len(items))
+
+	size := len(items) - start
+	if top, err := strconv.Atoi(q.Get("$top")); err == nil && top > 0 {
+		size = min(size, top)
+	}
+	if s.opts.PageSize > 0 {
+		size = min(size, s.opts.PageSize)
+	}
+
+	page := items[start : start+size]
+	if next := start + size; next < len(items) && size > 0 {
+		w.Header().Set("x-ms-continuationtoken", strconv.Itoa(next))
+	}
+	if page == nil {
+		page = []map[string]interface{}{}
+	}
+	writeCacheableJSON(w, r, map[string]interface{}{"count": len(page), "value": page})
+}
+
+func selectFields(item map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return item
+	}
+	all, _ := item["fields"].(map[string]interface{})
+	picked := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			picked[f] = v
+		}
+	}
+	out := make(map[string]interface{}, len(item))
+	for k, v := range item {
+		out[k] = v
+	}
+	out["fields"] = picked
+	return out
+}
+
+func match(item map[string]interface{}, key, filter string) bool {
+	return filter == "" || strings.EqualFold(str(item, key), filter)
+}
+
+func str(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func fieldStr(item map[string]interface{}, name string) string {
+	fields, _ := item["fields"].(map[string]interface{})
+	return str(fields, name)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeCacheableJSON writes v with an ETag, answering 304 Not Modified when
+// the request's If-None-Match matches it.
+func writeCacheableJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalServerException", err.Error())
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
+
+// versionNumber parses the major.minor part of an api-version such as
+// "7.1-preview.1".
+func versionNumber(v string) float64 {
+	v, _, _ = strings.Cut(v, "-")
+	f, _ := strconv.ParseFloat(v, 64)
+	return f
+}
+
+func writeError(w http.ResponseWriter, status int, typeKey, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"$id":       "1",
+		"message":   message,
+		"typeName":  "Microsoft.TeamFoundation.Framework.Server." + typeKey,
+		"typeKey":   typeKey,
+		"errorCode": 0,
+		"eventId":   3000,
+	})
+}
+
+// ExportFixtures writes the embedded fixtures to dir so they can be edited
+// and served with Options.FixturesDir.
+func ExportFixtures(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, name := range append(fixtureNames, optionalFixtureNames...) {
+		data, err := embedded.ReadFile("fixtures/" + name + ".json")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
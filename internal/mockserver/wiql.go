@@ -0,0 +1,144 @@
+package mockserver
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// wiqlFilter is the WHERE clause of a WIQL query, reduced to conditions
+// joined by AND. It understands enough WIQL for the queries apo and the
+// fixtures use: =, <>, IN, NOT IN and CONTAINS against literals, @Me and
+// @project. Anything else (OR, dates, macros with offsets) is ignored, so
+// the mock errs on the side of returning items.
+type wiqlFilter []wiqlCondition
+
+type wiqlCondition struct {
+	field  string
+	op     string   // "=", "<>", "in", "not in" or "contains"
+	values []string // unquoted; macros are kept as written
+}
+
+var (
+	wiqlWhere   = regexp.MustCompile(`(?is)\bwhere\b(.*?)(\border\s+by\b.*)?$`)
+	wiqlAnd     = regexp.MustCompile(`(?i)\s+and\s+`)
+	wiqlTerm    = regexp.MustCompile(`(?is)^\(?\s*\[([^\]]+)\]\s*(=|<>|not\s+in|in|contains)\s*(.+?)\)?\s*$`)
+	wiqlLiteral = regexp.MustCompile(`'((?:[^']|'')*)'|@\w+|-?\d+`)
+)
+
+// parseWIQL extracts the filter from a query.
+func parseWIQL(query string) wiqlFilter {
+	m := wiqlWhere.FindStringSubmatch(query)
+	if m == nil {
+		return nil
+	}
+	var filter wiqlFilter
+	for _, part := range splitOutsideQuotes(m[1]) {
+		c := wiqlTerm.FindStringSubmatch(strings.TrimSpace(part))
+		if c == nil {
+			continue
+		}
+		cond := wiqlCondition{field: c[1], op: strings.ToLower(strings.Join(strings.Fields(c[2]), " "))}
+		for _, lit := range wiqlLiteral.FindAllStringSubmatch(c[3], -1) {
+			if strings.HasPrefix(lit[0], "'") {
+				cond.values = append(cond.values, strings.ReplaceAll(lit[1], "''", "'"))
+			} else {
+				cond.values = append(cond.values, lit[0])
+			}
+		}
+		if len(cond.values) > 0 {
+			filter = append(filter, cond)
+		}
+	}
+	return filter
+}
+
+// splitOutsideQuotes splits a WHERE clause on AND, ignoring ANDs inside
+// string literals.
+func splitOutsideQuotes(clause string) []string {
+	// Blank out literals so the AND pattern cannot match inside them; the
+	// masked string keeps every byte offset of the original.
+	masked := []byte(clause)
+	inQuote := false
+	for i, b := range masked {
+		if b == '\'' {
+			inQuote = !inQuote
+		} else if inQuote {
+			masked[i] = 'x'
+		}
+	}
+	var parts []string
+	start := 0
+	for _, loc := range wiqlAnd.FindAllIndex(masked, -1) {
+		parts = append(parts, clause[start:loc[0]])
+		start = loc[1]
+	}
+	return append(parts, clause[start:])
+}
+
+// namesProjects reports whether the filter limits [System.TeamProject] to
+// projects it lists, rather than to the one the query is sent to.
+func (f wiqlFilter) namesProjects() bool {
+	for _, c := range f {
+		if strings.EqualFold(c.field, "System.TeamProject") && (c.op == "=" || c.op == "in") &&
+			!slices.ContainsFunc(c.values, func(v string) bool { return strings.EqualFold(v, "@project") }) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a fixture work item satisfies every condition.
+// The mock treats every work item as assigned to the caller.
+func (f wiqlFilter) matches(item map[string]interface{}, project string) bool {
+	for _, c := range f {
+		if !c.matches(item, project) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c wiqlCondition) matches(item map[string]interface{}, project string) bool {
+	fields, _ := item["fields"].(map[string]interface{})
+	actual := fieldText(fields[c.field])
+	equal := func(want string) bool {
+		switch strings.ToLower(want) {
+		case "@me":
+			return true
+		case "@project":
+			want = project
+		}
+		return strings.EqualFold(actual, want)
+	}
+	switch c.op {
+	case "=":
+		return equal(c.values[0])
+	case "<>":
+		return !equal(c.values[0])
+	case "in", "not in":
+		found := false
+		for _, v := range c.values {
+			found = found || equal(v)
+		}
+		return found == (c.op == "in")
+	case "contains":
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(c.values[0]))
+	}
+	return true
+}
+
+// fieldText renders a field value for comparison. Identities compare by
+// display name.
+func fieldText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}:
+		return str(v, "displayName")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,251 @@
+// Package secret encrypts credentials stored in the config file.
+//
+// Values are sealed with AES-256-GCM under a key that comes either from a
+// random machine key file readable only by its owner, or from a passphrase
+// run through PBKDF2-HMAC-SHA256. A sealed value records which of the two
+// was used, so it can be opened without further configuration:
+//
+//	enc:key:<base64 nonce+ciphertext>
+//	enc:pass:<base64 salt+nonce+ciphertext>
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store kinds, as named in the config file.
+const (
+	KeyFile    = "keyfile"
+	Passphrase = "passphrase"
+)
+
+const (
+	keyPrefix  = "enc:key:"
+	passPrefix = "enc:pass:"
+
+	keySize    = 32
+	saltSize   = 16
+	iterations = 600_000 // OWASP's 2023 recommendation for PBKDF2-HMAC-SHA256
+)
+
+// ErrNoPassphrase is returned when a value needs a passphrase and none is
+// available.
+var ErrNoPassphrase = errors.New("a passphrase is required to decrypt the PAT (set APO_PASSPHRASE)")
+
+// Box seals and opens values. The zero value is not usable; use New.
+type Box struct {
+	keyPath    string
+	passphrase func() (string, error)
+
+	key  []byte // machine key, read on first use
+	pass string // passphrase, asked for on first use
+}
+
+// New returns a Box that keeps its machine key at keyPath and asks
+// passphrase for the passphrase when one is first needed. passphrase may be
+// nil, in which case only APO_PASSPHRASE is consulted.
+func New(keyPath string, passphrase func() (string, error)) *Box {
+	return &Box{keyPath: keyPath, passphrase: passphrase}
+}
+
+// SetPassphrase replaces the passphrase used from now on.
+func (b *Box) SetPassphrase(passphrase string) {
+	b.pass = passphrase
+}
+
+// Seal encrypts plaintext with the given kind of key.
+func (b *Box) Seal(kind, plaintext string) (string, error) {
+	switch kind {
+	case KeyFile:
+		key, err := b.machineKey(true)
+		if err != nil {
+			return "", err
+		}
+		sealed, err := seal(key, []byte(plaintext))
+		if err != nil {
+			return "", err
+		}
+		return keyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+	case Passphrase:
+		pass, err := b.getPassphrase()
+		if err != nil {
+			return "", err
+		}
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		sealed, err := seal(deriveKey(pass, salt), []byte(plaintext))
+		if err != nil {
+			return "", err
+		}
+		return passPrefix + base64.StdEncoding.EncodeToString(append(salt, sealed...)), nil
+	}
+	return "", fmt.Errorf("unknown credential store %q (want %s or %s)", kind, KeyFile, Passphrase)
+}
+
+// Open decrypts a value produced by Seal.
+func (b *Box) Open(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, keyPrefix):
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, keyPrefix))
+		if err != nil {
+			return "", fmt.Errorf("decoding encrypted PAT: %w", err)
+		}
+		key, err := b.machineKey(false)
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := open(key, data)
+		if err != nil {
+			return "", fmt.Errorf("decrypting PAT: wrong or replaced key file %s", b.keyPath)
+		}
+		return string(plaintext), nil
+	case strings.HasPrefix(value, passPrefix):
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, passPrefix))
+		if err != nil || len(data) < saltSize {
+			return "", fmt.Errorf("decoding encrypted PAT: malformed value")
+		}
+		pass, err := b.getPassphrase()
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := open(deriveKey(pass, data[:saltSize]), data[saltSize:])
+		if err != nil {
+			return "", fmt.Errorf("decrypting PAT: wrong passphrase")
+		}
+		return string(plaintext), nil
+	}
+	return "", fmt.Errorf("decrypting PAT: not an encrypted value")
+}
+
+// machineKey reads the key file, creating it first when create is set and
+// it does not exist. A key file others can read is refused.
+func (b *Box) machineKey(create bool) ([]byte, error) {
+	if b.key != nil {
+		return b.key, nil
+	}
+	info, err := os.Stat(b.keyPath)
+	if os.IsNotExist(err) && create {
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(b.keyPath), 0700); err != nil {
+			return nil, fmt.Errorf("creating key directory: %w", err)
+		}
+		if err := os.WriteFile(b.keyPath, key, 0600); err != nil {
+			return nil, fmt.Errorf("writing key file: %w", err)
+		}
+		b.key = key
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("key file %s is accessible by other users; run chmod 600 on it", b.keyPath)
+	}
+	key, err := os.ReadFile(b.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key file %s is corrupt", b.keyPath)
+	}
+	b.key = key
+	return key, nil
+}
+
+func (b *Box) getPassphrase() (string, error) {
+	if b.pass != "" {
+		return b.pass, nil
+	}
+	pass := os.Getenv("APO_PASSPHRASE")
+	if pass == "" && b.passphrase != nil {
+		var err error
+		if pass, err = b.passphrase(); err != nil {
+			return "", err
+		}
+	}
+	if pass == "" {
+		return "", ErrNoPassphrase
+	}
+	b.pass = pass
+	return pass, nil
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey stretches a passphrase into an AES-256 key.
+func deriveKey(passphrase string, salt []byte) []byte {
+	return pbkdf2(sha256.New, []byte(passphrase), salt, iterations, keySize)
+}
+
+// pbkdf2 implements PBKDF2 (RFC 8018, section 5.2). crypto/pbkdf2 needs a
+// newer Go than go.mod allows.
+func pbkdf2(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u = prf.Sum(u[:0])
+		t := append([]byte{}, u...)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		dk = append(dk, t...)
+	}
+	return dk[:keyLen]
+}
@@ -0,0 +1,158 @@
+package secret
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Setenv("APO_PASSPHRASE", "")
+	const pat = "q7v3c5xk2mz4lh6r8ytp0wn9sdbfj1ga3eukx5ic7oqr2lzt4hna"
+
+	tests := []struct {
+		kind   string
+		prefix string
+	}{
+		{KeyFile, keyPrefix},
+		{Passphrase, passPrefix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			keyPath := filepath.Join(t.TempDir(), "key")
+			box := New(keyPath, func() (string, error) { return "correct horse", nil })
+
+			sealed, err := box.Seal(tt.kind, pat)
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+			if !strings.HasPrefix(sealed, tt.prefix) || strings.Contains(sealed, pat) {
+				t.Fatalf("Seal = %q, want an opaque %s value", sealed, tt.prefix)
+			}
+			again, err := box.Seal(tt.kind, pat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again == sealed {
+				t.Error("sealing the same PAT twice gave the same value")
+			}
+
+			// A fresh box, as in the next run of apo, opens both.
+			fresh := New(keyPath, func() (string, error) { return "correct horse", nil })
+			for _, v := range []string{sealed, again} {
+				got, err := fresh.Open(v)
+				if err != nil {
+					t.Fatalf("Open: %v", err)
+				}
+				if got != pat {
+					t.Errorf("Open = %q, want %q", got, pat)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenFailures(t *testing.T) {
+	t.Setenv("APO_PASSPHRASE", "")
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+	box := New(keyPath, func() (string, error) { return "right", nil })
+	byKey, err := box.Seal(KeyFile, "pat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPass, err := box.Seal(Passphrase, "pat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		b := New(keyPath, func() (string, error) { return "wrong", nil })
+		if _, err := b.Open(byPass); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+			t.Errorf("Open = %v, want a wrong passphrase error", err)
+		}
+	})
+	t.Run("no passphrase", func(t *testing.T) {
+		if _, err := New(keyPath, nil).Open(byPass); !errors.Is(err, ErrNoPassphrase) {
+			t.Errorf("Open = %v, want ErrNoPassphrase", err)
+		}
+	})
+	t.Run("passphrase from the environment", func(t *testing.T) {
+		t.Setenv("APO_PASSPHRASE", "right")
+		if got, err := New(keyPath, nil).Open(byPass); err != nil || got != "pat" {
+			t.Errorf("Open = %q, %v", got, err)
+		}
+	})
+	t.Run("missing key file", func(t *testing.T) {
+		if _, err := New(filepath.Join(dir, "none"), nil).Open(byKey); err == nil {
+			t.Error("opened a value without its key file")
+		}
+	})
+	t.Run("replaced key file", func(t *testing.T) {
+		other := filepath.Join(dir, "other")
+		if _, err := New(other, nil).Seal(KeyFile, "x"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(other, nil).Open(byKey); err == nil || !strings.Contains(err.Error(), "wrong or replaced key file") {
+			t.Errorf("Open = %v, want a replaced key file error", err)
+		}
+	})
+	t.Run("key file readable by others", func(t *testing.T) {
+		loose := filepath.Join(dir, "loose")
+		data, err := os.ReadFile(keyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(loose, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(loose, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(loose, nil).Open(byKey); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+			t.Errorf("Open = %v, want a permissions error", err)
+		}
+	})
+	t.Run("tampered value", func(t *testing.T) {
+		tampered := byKey[:len(byKey)-4] + "AAA="
+		if _, err := box.Open(tampered); err == nil {
+			t.Error("opened a tampered value")
+		}
+	})
+	t.Run("plaintext", func(t *testing.T) {
+		if _, err := box.Open("pat"); err == nil {
+			t.Error("opened a value that was never sealed")
+		}
+	})
+	t.Run("unknown kind", func(t *testing.T) {
+		if _, err := box.Seal("keychain", "pat"); err == nil {
+			t.Error("sealed with an unknown store")
+		}
+	})
+}
+
+// TestPBKDF2 checks the hand-rolled PBKDF2 against published
+// PBKDF2-HMAC-SHA256 vectors, including one spanning two blocks.
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iter, keyLen   int
+		want           string
+	}{
+		{"password", "salt", 1, 32, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, 32, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, 32, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 40,
+			"348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1c635518c7dac47e9"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2(sha256.New, []byte(tt.password), []byte(tt.salt), tt.iter, tt.keyLen))
+		if got != tt.want {
+			t.Errorf("pbkdf2(%q, %q, %d, %d) = %s, want %s", tt.password, tt.salt, tt.iter, tt.keyLen, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package snapshot persists the last successfully loaded dataset so apo can
+// start instantly and keep working when Azure DevOps is unreachable.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/user/apo/internal/domain"
+)
+
+// Snapshot is a saved dataset for one organization and project.
+type Snapshot struct {
+	SavedAt      time.Time            `json:"saved_at"`
+	WorkItems    []domain.WorkItem    `json:"work_items"`
+	Builds       []domain.Build       `json:"builds"`
+	Pipelines    []domain.Pipeline    `json:"pipelines"`
+	Repositories []domain.Repository  `json:"repositories"`
+	PullRequests []domain.PullRequest `json:"pull_requests"`
+}
+
+// Age returns how long ago the snapshot was saved.
+func (s *Snapshot) Age() time.Duration {
+	return time.Since(s.SavedAt)
+}
+
+// Store reads and writes the snapshot for one organization and project.
+type Store struct {
+	path string
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// NewStore returns the store for org/project inside dir.
+func NewStore(dir, org, project string) *Store {
+	name := unsafeChars.ReplaceAllString(org+"_"+project, "-") + ".json"
+	return &Store{path: filepath.Join(dir, name)}
+}
+
+// Load reads the snapshot. It returns nil and no error if none was saved.
+func (s *Store) Load() (*Snapshot, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parsing snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// Save writes the snapshot, stamping it with the current time.
+func (s *Store) Save(snap *Snapshot) error {
+	snap.SavedAt = time.Now()
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -2,6 +2,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/user/apo/internal/api"
 	"github.com/user/apo/internal/config"
 	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/snapshot"
 	"github.com/user/apo/internal/ui/components"
 	"github.com/user/apo/internal/ui/terminal"
 	"github.com/user/apo/internal/ui/views"
@@ -20,12 +22,20 @@ import (
 // App is the main TUI application.
 type App struct {
 	term   *terminal.Terminal
-	client *api.Client
+	client api.Service
 	agent  *agent.Agent
 	config *config.Config
 
-	tabBar    *components.TabBar
-	statusBar *components.StatusBar
+	tabBar       *components.TabBar
+	statusBar    *components.StatusBar
+	picker       *components.Picker
+	onPick       func(value string)
+	prompt       *components.Prompt
+	onSubmit     func(value string)
+	form         *components.Form
+	onFormChange func(name string) // called with mu held after a field is picked
+	onFormSubmit func(values map[string]string)
+	newClient    func(*config.Config) api.Service
 
 	dashboard      *views.DashboardView
 	boards         *views.BoardsView
@@ -40,24 +50,38 @@ type App struct {
 	currentView  views.ViewID
 	previousView views.ViewID
 
-	mu          sync.RWMutex
-	workItems   []domain.WorkItem
-	builds      []domain.Build
+	ctx           context.Context
+	cancel        context.CancelFunc
+	refreshCancel context.CancelFunc
+	pollCancel    context.CancelFunc
+	redraw        chan struct{}
+	tasks         chan func()
+
+	mu           sync.RWMutex
+	workItems    []domain.WorkItem
+	builds       []domain.Build
 	pipelineList []domain.Pipeline
-	repoList    []domain.Repository
-	prList      []domain.PullRequest
-	lastRefresh time.Time
-	loading     bool
+	repoList     []domain.Repository
+	prList       []domain.PullRequest
+	lastRefresh  time.Time
+
+	snapshots  *snapshot.Store
+	staleSince time.Time // when the displayed snapshot was saved; zero once fresh
+	offline    bool
+	orgWide    bool // aggregate across projects; see orgwide.go
+
+	loaded   map[section]bool // sections loaded live at least once
+	inflight map[section]int
+	marks    map[section]map[string]mark
 }
 
-// NewApp creates a new TUI application.
-func NewApp(cfg *config.Config) (*App, error) {
+// NewApp creates a new TUI application that reads data from client.
+func NewApp(cfg *config.Config, client api.Service) (*App, error) {
 	if err := cfg.ValidateWithProject(); err != nil {
 		return nil, err
 	}
 
 	term := terminal.New()
-	client := api.NewClient(cfg)
 	ag := agent.New(client)
 
 	tabs := []components.Tab{
@@ -70,8 +94,9 @@ func NewApp(cfg *config.Config) (*App, error) {
 	}
 
 	detailCfg := details.DetailConfig{
-		Organization: cfg.Organization,
-		Project:      cfg.Project,
+		Organization:    cfg.Organization,
+		OrganizationURL: cfg.OrganizationURL(),
+		Project:         cfg.Project,
 	}
 
 	app := &App{
@@ -81,6 +106,9 @@ func NewApp(cfg *config.Config) (*App, error) {
 		config:         cfg,
 		tabBar:         components.NewTabBar(term, tabs),
 		statusBar:      components.NewStatusBar(term),
+		picker:         components.NewPicker(term),
+		prompt:         components.NewPrompt(term),
+		form:           components.NewForm(term),
 		dashboard:      views.NewDashboardView(term),
 		boards:         views.NewBoardsView(term),
 		pipelines:      views.NewPipelinesView(term),
@@ -90,21 +118,37 @@ func NewApp(cfg *config.Config) (*App, error) {
 		workItemDetail: details.NewWorkItemDetailView(term, detailCfg),
 		prDetail:       details.NewPRDetailView(term, detailCfg),
 		currentView:    views.ViewDashboard,
+		redraw:         make(chan struct{}, 1),
+		tasks:          make(chan func()),
+		snapshots:      snapshot.NewStore(config.GetSnapshotDir(), cfg.CollectionName(), cfg.Project),
+		loaded:         make(map[section]bool),
+		inflight:       make(map[section]int),
+		marks:          make(map[section]map[string]mark),
 	}
 
 	app.boards.OnSelectItem(func(item *domain.WorkItem) {
 		app.showWorkItemDetail(item)
 	})
 
+	app.workItemDetail.OnEdit(app.editWorkItem)
+	app.workItemDetail.OnComment(app.commentOnWorkItem)
+	app.workItemDetail.OnHistory(app.showHistory)
+
 	app.prs.OnSelectItem(func(pr *domain.PullRequest) {
 		app.showPRDetail(pr)
 	})
 
+	app.copilot.OnUpdate(app.requestRedraw)
+
 	return app, nil
 }
 
-// Run starts the TUI.
-func (a *App) Run() error {
+// Run starts the TUI. It returns when the user quits or ctx is canceled;
+// either way, outstanding API requests are canceled.
+func (a *App) Run(ctx context.Context) error {
+	a.ctx, a.cancel = context.WithCancel(ctx)
+	defer a.cancel()
+	a.copilot.SetContext(a.ctx)
 	a.running = true
 
 	a.term.EnableRawMode()
@@ -113,28 +157,94 @@ func (a *App) Run() error {
 	defer a.term.ShowCursor()
 	a.term.Clear()
 
+	a.loadSnapshot()
 	a.setStatus("Loading...")
-	go a.refreshData()
+	a.startRefresh(false)
+	a.startPolling()
+
+	keys := make(chan terminal.Key)
+	go a.readKeys(keys)
 
 	for a.running {
 		a.render()
+		select {
+		case key := <-keys:
+			a.handleInput(key)
+		case <-a.redraw:
+		case fn := <-a.tasks:
+			fn()
+		case <-a.ctx.Done():
+			a.quit()
+		}
+	}
+
+	a.term.Clear()
+	return nil
+}
+
+// readKeys forwards key presses to keys until the app stops.
+func (a *App) readKeys(keys chan<- terminal.Key) {
+	for {
 		key, err := a.term.ReadKey()
 		if err != nil {
 			continue
 		}
-		a.handleInput(key)
+		select {
+		case keys <- key:
+		case <-a.ctx.Done():
+			return
+		}
 	}
+}
 
-	a.term.Clear()
-	return nil
+// requestRedraw asks the main loop to render again. It is safe to call from
+// any goroutine.
+func (a *App) requestRedraw() {
+	select {
+	case a.redraw <- struct{}{}:
+	default:
+	}
+}
+
+// post runs fn on the main loop, which owns the current view and the
+// picker, prompt and form: background loads open those through post, so
+// key handling never sees them change. It is safe to call from any
+// goroutine.
+func (a *App) post(fn func()) {
+	select {
+	case a.tasks <- fn:
+	case <-a.ctx.Done():
+	}
+}
+
+func (a *App) quit() {
+	a.running = false
+	a.cancel()
 }
 
 func (a *App) handleInput(key terminal.Key) {
 	switch key.Type {
 	case terminal.KeyCtrlC:
-		a.running = false
+		a.quit()
+		return
+	}
+
+	if a.picker.IsOpen() {
+		a.handlePickerKey(key)
+		return
+	}
+
+	if a.prompt.IsOpen() {
+		a.handlePromptKey(key)
+		return
+	}
+
+	if a.form.IsOpen() {
+		a.handleFormKey(key)
 		return
-	case terminal.KeyEscape:
+	}
+
+	if key.Type == terminal.KeyEscape {
 		if a.isDetailView() {
 			a.currentView = a.previousView
 			return
@@ -143,7 +253,13 @@ func (a *App) handleInput(key terminal.Key) {
 			a.switchToView(views.ViewDashboard)
 			return
 		}
-		return
+		if a.currentView == views.ViewBoards && !a.isFilterMode() && a.boards.Query() != "" {
+			a.clearSavedQuery()
+			return
+		}
+		if !a.isFilterMode() {
+			return
+		}
 	}
 
 	if a.getCurrentView().HandleKey(key) {
@@ -154,7 +270,7 @@ func (a *App) handleInput(key terminal.Key) {
 	case terminal.KeyRune:
 		switch key.Rune {
 		case 'q', 'Q':
-			a.running = false
+			a.quit()
 		case '1':
 			a.switchToView(views.ViewDashboard)
 		case '2':
@@ -167,9 +283,26 @@ func (a *App) handleInput(key terminal.Key) {
 			a.switchToView(views.ViewPullRequests)
 		case '/', ':':
 			a.switchToView(views.ViewCopilot)
-		case 'r', 'R':
+		case 'r':
 			a.setStatus("Refreshing...")
-			go a.refreshData()
+			a.startRefresh(false)
+		case 'R':
+			a.setStatus("Refreshing (bypassing cache)...")
+			a.startRefresh(true)
+		case 'o':
+			a.toggleOrgWide()
+		case 's':
+			if a.currentView == views.ViewBoards {
+				a.openQueryPicker()
+			}
+		case 'n':
+			if a.currentView == views.ViewBoards {
+				a.openCreateForm()
+			}
+		case 'p':
+			a.openProjectPicker()
+		case 'P':
+			a.openProfilePicker()
 		case 'b':
 			if a.isDetailView() {
 				a.currentView = a.previousView
@@ -255,9 +388,12 @@ func (a *App) switchToTabView() {
 }
 
 func (a *App) showWorkItemDetail(item *domain.WorkItem) {
+	a.mu.Lock()
 	a.workItemDetail.SetWorkItem(item)
+	a.mu.Unlock()
 	a.previousView = a.currentView
 	a.currentView = views.ViewWorkItemDetail
+	a.loadComments(*item, false)
 }
 
 func (a *App) showPRDetail(pr *domain.PullRequest) {
@@ -266,49 +402,55 @@ func (a *App) showPRDetail(pr *domain.PullRequest) {
 	a.currentView = views.ViewPRDetail
 }
 
-func (a *App) refreshData() {
-	a.loading = true
-	a.mu.Lock()
-
-	if items, err := a.client.GetMyWorkItems(); err == nil {
-		a.workItems = items
-		a.boards.SetWorkItems(items)
-	}
-
-	if builds, err := a.client.ListBuilds("", "", 20); err == nil {
-		a.builds = builds
-	}
-
-	if pipelines, err := a.client.ListPipelines(); err == nil {
-		a.pipelineList = pipelines
-		a.pipelines.SetPipelines(pipelines)
+// startRefresh reloads all data in the background, canceling any refresh
+// still in flight. With bypassCache, cached responses are revalidated even
+// if still fresh.
+func (a *App) startRefresh(bypassCache bool) {
+	if a.refreshCancel != nil {
+		a.refreshCancel()
 	}
-
-	if repos, err := a.client.ListRepositories(); err == nil {
-		a.repoList = repos
-		a.repos.SetRepositories(repos)
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.refreshCancel = cancel
+	if bypassCache {
+		ctx = api.WithCacheBypass(ctx)
 	}
+	go a.refreshData(ctx)
+}
 
-	if prs, err := a.client.GetActivePullRequests(20); err == nil {
-		a.prList = prs
-		a.prs.SetPullRequests(prs)
+// loadSnapshot shows the last saved dataset, marked stale, until the first
+// refresh completes.
+func (a *App) loadSnapshot() {
+	snap, err := a.snapshots.Load()
+	if err != nil || snap == nil {
+		return
 	}
 
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.workItems = snap.WorkItems
+	a.builds = snap.Builds
+	a.pipelineList = snap.Pipelines
+	a.repoList = snap.Repositories
+	a.prList = snap.PullRequests
+	a.boards.SetWorkItems(a.workItems)
+	a.pipelines.SetPipelines(a.pipelineList)
+	a.repos.SetRepositories(a.repoList)
+	a.prs.SetPullRequests(a.prList)
 	a.dashboard.SetData(a.workItems, a.builds, a.prList)
-
-	a.lastRefresh = time.Now()
-	a.loading = false
-	a.mu.Unlock()
-
-	a.statusBar.SetLastRefresh(a.lastRefresh)
-	a.setStatus("Data refreshed")
+	a.staleSince = snap.SavedAt
+	a.statusBar.SetStaleSince(a.staleSince, false)
 }
 
 func (a *App) setStatus(msg string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.statusBar.SetMessage(msg)
 }
 
 func (a *App) render() {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	a.term.Clear()
 	width := a.term.Width()
 	height := a.term.Height()
@@ -322,17 +464,13 @@ func (a *App) render() {
 	contentStart := 5
 	contentHeight := height - 7
 
-	if a.loading {
-		a.term.MoveTo(contentStart+contentHeight/2, width/2-10)
-		fmt.Print(terminal.Style("⏳ Loading...", terminal.FgYellow, terminal.Bold))
-	} else {
-		a.mu.RLock()
-		a.getCurrentView().Render(contentStart, width, contentHeight)
-		a.mu.RUnlock()
-	}
+	a.getCurrentView().Render(contentStart, width, contentHeight)
 
 	a.updateHelpText()
 	a.statusBar.Render(height-2, width)
+	a.form.Render(width, height)
+	a.picker.Render(width, height)
+	a.prompt.Render(width, height)
 }
 
 func (a *App) renderHeader(width int) {
@@ -342,22 +480,51 @@ func (a *App) renderHeader(width int) {
 	fmt.Print(strings.Repeat(" ", padding))
 	fmt.Print(terminal.Style(title, terminal.Bold, terminal.FgCyan))
 
-	orgInfo := fmt.Sprintf("  %s/%s", a.config.Organization, a.config.Project)
+	orgInfo := fmt.Sprintf("  %s/%s", a.config.CollectionName(), a.config.Project)
+	if a.orgWide {
+		orgInfo = fmt.Sprintf("  %s/* (org-wide)", a.config.CollectionName())
+	}
+	if len(a.config.Profiles) > 0 {
+		orgInfo = fmt.Sprintf("  [%s]%s", a.config.ProfileName(), orgInfo)
+	}
 	a.term.MoveTo(1, width-len(orgInfo)-1)
 	fmt.Print(terminal.Style(orgInfo, terminal.Dim))
+
+	if !a.staleSince.IsZero() {
+		label := "⏳ Cached data from " + components.FormatAge(time.Since(a.staleSince)) + " ago"
+		if a.offline {
+			label = "⚠ Offline — showing data from " + components.FormatAge(time.Since(a.staleSince)) + " ago"
+		}
+		a.term.MoveTo(1, 2)
+		fmt.Print(terminal.Style(label, terminal.Bold, terminal.FgYellow))
+	}
 }
 
 func (a *App) updateHelpText() {
 	var help string
 	switch {
+	case a.picker.IsOpen():
+		help = " Type to search │ [↑↓] Navigate │ [Enter] Select │ [Esc] Cancel "
+	case a.prompt.IsOpen():
+		help = " Type to edit │ [Enter] Save │ [Esc] Cancel "
+	case a.form.IsOpen():
+		help = " Type to edit │ [↑↓/Tab] Move │ [Enter] Pick/Next │ [Esc] Cancel "
 	case a.currentView == views.ViewCopilot:
 		help = " [Enter] Send │ [Esc] Back │ [Ctrl+C] Quit "
+	case a.currentView == views.ViewWorkItemDetail && a.workItemDetail.HistoryShown():
+		help = " [↑↓] Revision │ [Tab/h] Details │ [e/s/a/t] Edit │ [c] Comment │ [Esc/b] Back │ [q] Quit "
+	case a.currentView == views.ViewWorkItemDetail:
+		help = " [↑↓] Scroll │ [Tab/h] History │ [e/s/a/t] Edit │ [c] Comment │ [Esc/b] Back │ [q] Quit "
 	case a.isDetailView():
 		help = " [Esc/b] Back │ [q] Quit "
 	case a.isFilterMode():
 		help = " [Enter] Apply │ [Esc] Cancel │ Type to filter... "
+	case a.currentView == views.ViewBoards && a.boards.Query() != "":
+		help = " [↑↓/jk] Navigate │ [Enter] Details │ [f] Filter │ [n] New │ [s] Saved query │ [Esc] My work items │ [q] Quit "
+	case a.currentView == views.ViewBoards:
+		help = " [1-5] Tab │ [↑↓/jk] Navigate │ [Enter] Details │ [f] Filter │ [n] New │ [s] Saved query │ [r/R] Refresh │ [q] Quit "
 	default:
-		help = " [1-5] Tab │ [/] Copilot │ [↑↓/jk] Navigate │ [Enter] Details │ [f] Filter │ [r] Refresh │ [q] Quit "
+		help = " [1-5] Tab │ [/] Copilot │ [↑↓/jk] Navigate │ [Enter] Details │ [f] Filter │ [r/R] Refresh │ [o] Org-wide │ [p/P] Project/Profile │ [q] Quit "
 	}
 	a.statusBar.SetHelp(help)
 }
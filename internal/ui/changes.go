@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/components"
+	"github.com/user/apo/internal/ui/views"
+)
+
+// highlightTTL is how long a changed row stays highlighted.
+const highlightTTL = 5 * time.Minute
+
+// mark is a highlight and when it was recorded.
+type mark struct {
+	highlight components.Highlight
+	at        time.Time
+}
+
+// diffWorkItems compares two loads of work items. Items are new when their
+// ID was not present before and changed when their revision moved.
+func diffWorkItems(old, cur []domain.WorkItem) (map[string]components.Highlight, []string) {
+	prev := make(map[int]domain.WorkItem, len(old))
+	for _, item := range old {
+		prev[item.ID] = item
+	}
+
+	changes := make(map[string]components.Highlight)
+	var notes []string
+	for _, item := range cur {
+		id := fmt.Sprintf("%d", item.ID)
+		before, ok := prev[item.ID]
+		switch {
+		case !ok:
+			changes[id] = components.HighlightNew
+			notes = append(notes, fmt.Sprintf("New work item #%d: %s", item.ID, item.Title()))
+		case item.Rev != before.Rev:
+			changes[id] = components.HighlightChanged
+			if item.State() != before.State() {
+				notes = append(notes, fmt.Sprintf("#%d moved to %s", item.ID, item.State()))
+			}
+		}
+	}
+	return changes, notes
+}
+
+// diffBuilds compares two loads of builds. A build that is new and failed,
+// or that has just finished with a failure, needs attention.
+func diffBuilds(old, cur []domain.Build) (map[string]components.Highlight, []string) {
+	prev := make(map[int]domain.Build, len(old))
+	for _, b := range old {
+		prev[b.ID] = b
+	}
+
+	changes := make(map[string]components.Highlight)
+	var notes []string
+	for _, b := range cur {
+		id := fmt.Sprintf("%d", b.ID)
+		before, ok := prev[b.ID]
+		failed := b.Result == "failed" && (!ok || before.Result != "failed")
+		switch {
+		case failed:
+			changes[id] = components.HighlightAlert
+			notes = append(notes, fmt.Sprintf("New failed build: %s #%s", b.Definition.Name, b.BuildNumber))
+		case !ok:
+			changes[id] = components.HighlightNew
+		case b.Status != before.Status || b.Result != before.Result:
+			changes[id] = components.HighlightChanged
+		}
+	}
+	return changes, notes
+}
+
+// diffPullRequests compares two loads of pull requests. New reviewer votes
+// mark a pull request as changed, and a rejection needs attention.
+func diffPullRequests(old, cur []domain.PullRequest) (map[string]components.Highlight, []string) {
+	prev := make(map[int]domain.PullRequest, len(old))
+	for _, pr := range old {
+		prev[pr.PullRequestID] = pr
+	}
+
+	changes := make(map[string]components.Highlight)
+	var notes []string
+	for _, pr := range cur {
+		id := fmt.Sprintf("%d", pr.PullRequestID)
+		before, ok := prev[pr.PullRequestID]
+		if !ok {
+			changes[id] = components.HighlightNew
+			notes = append(notes, fmt.Sprintf("New pull request #%d: %s", pr.PullRequestID, pr.Title))
+			continue
+		}
+
+		votes := make(map[string]int, len(before.Reviewers))
+		for _, r := range before.Reviewers {
+			votes[r.ID] = r.Vote
+		}
+		for _, r := range pr.Reviewers {
+			if votes[r.ID] == r.Vote { // reviewers added without a vote count as 0
+				continue
+			}
+			if r.Vote == -10 {
+				changes[id] = components.HighlightAlert
+			} else if changes[id] != components.HighlightAlert {
+				changes[id] = components.HighlightChanged
+			}
+			notes = append(notes, fmt.Sprintf("%s: %s on #%d", r.DisplayName, r.VoteStatus(), pr.PullRequestID))
+		}
+	}
+	return changes, notes
+}
+
+// diffPipelines compares two loads of pipelines. A pipeline moved to
+// another folder or renamed counts as changed.
+func diffPipelines(old, cur []domain.Pipeline) (map[string]components.Highlight, []string) {
+	prev := make(map[int]domain.Pipeline, len(old))
+	for _, p := range old {
+		prev[p.ID] = p
+	}
+
+	changes := make(map[string]components.Highlight)
+	var notes []string
+	for _, p := range cur {
+		id := fmt.Sprintf("%d", p.ID)
+		before, ok := prev[p.ID]
+		switch {
+		case !ok:
+			changes[id] = components.HighlightNew
+			notes = append(notes, fmt.Sprintf("New pipeline: %s", p.FullPath()))
+		case p.FullPath() != before.FullPath():
+			changes[id] = components.HighlightChanged
+		}
+	}
+	return changes, notes
+}
+
+// diffRepositories compares two loads of repositories. A renamed repository
+// or one with a new default branch counts as changed.
+func diffRepositories(old, cur []domain.Repository) (map[string]components.Highlight, []string) {
+	prev := make(map[string]domain.Repository, len(old))
+	for _, r := range old {
+		prev[r.ID] = r
+	}
+
+	changes := make(map[string]components.Highlight)
+	var notes []string
+	for _, r := range cur {
+		before, ok := prev[r.ID]
+		switch {
+		case !ok:
+			changes[r.ID] = components.HighlightNew
+			notes = append(notes, fmt.Sprintf("New repository: %s", r.Name))
+		case r.Name != before.Name || r.DefaultBranch != before.DefaultBranch:
+			changes[r.ID] = components.HighlightChanged
+		}
+	}
+	return changes, notes
+}
+
+// recordChanges adds highlights for a section, replacing earlier marks on
+// the same rows. It must be called with a.mu held.
+func (a *App) recordChanges(sec section, changes map[string]components.Highlight) {
+	if len(changes) == 0 {
+		return
+	}
+	marks := a.marks[sec]
+	if marks == nil {
+		marks = make(map[string]mark)
+		a.marks[sec] = marks
+	}
+	now := time.Now()
+	for id, h := range changes {
+		marks[id] = mark{highlight: h, at: now}
+	}
+}
+
+// applyHighlights drops expired marks for a section and shows the rest in
+// the views displaying it. It must be called with a.mu held.
+func (a *App) applyHighlights(sec section) {
+	highlights := make(map[string]components.Highlight)
+	for id, m := range a.marks[sec] {
+		if time.Since(m.at) > highlightTTL {
+			delete(a.marks[sec], id)
+			continue
+		}
+		highlights[id] = m.highlight
+	}
+
+	switch sec {
+	case sectionWorkItems:
+		a.boards.SetHighlights(highlights)
+		a.dashboard.SetHighlights(views.DashboardWorkItems, highlights)
+	case sectionBuilds:
+		a.dashboard.SetHighlights(views.DashboardBuilds, highlights)
+	case sectionPipelines:
+		a.pipelines.SetHighlights(highlights)
+	case sectionRepositories:
+		a.repos.SetHighlights(highlights)
+	case sectionPullRequests:
+		a.prs.SetHighlights(highlights)
+		a.dashboard.SetHighlights(views.DashboardPullRequests, highlights)
+	}
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/components"
+)
+
+// loadComments loads the discussion of the work item in the detail view in
+// the background, scrolling to the end when toBottom is set.
+func (a *App) loadComments(item domain.WorkItem, toBottom bool) {
+	a.mu.Lock()
+	a.workItemDetail.SetComments(item.ID, a.commentsShown(item.ID), components.LoadState{Loading: true})
+	a.mu.Unlock()
+	svc, ctx := a.workItemService(item), a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		comments, err := svc.WorkItemComments(ctx, item.ID)
+		if ctx.Err() != nil {
+			return
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		state := components.LoadState{}
+		if err != nil {
+			state.Error = api.Summary(err)
+			comments = a.commentsShown(item.ID)
+		}
+		a.workItemDetail.SetComments(item.ID, comments, state)
+		if toBottom && err == nil {
+			a.workItemDetail.ScrollToBottom()
+		}
+	}()
+}
+
+// commentsShown returns the comments the detail view shows for work item
+// id, so a reload keeps them on screen until it completes.
+func (a *App) commentsShown(id int) []domain.Comment {
+	if item := a.workItemDetail.WorkItem(); item != nil && item.ID == id {
+		return a.workItemDetail.Comments()
+	}
+	return nil
+}
+
+// commentOnWorkItem asks for a comment on the work item in the detail view
+// and posts it.
+func (a *App) commentOnWorkItem() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	shown := a.workItemDetail.WorkItem()
+	if shown == nil {
+		return
+	}
+	item := *shown
+	a.prompt.Open(fmt.Sprintf("Comment on #%d", item.ID), "", "@name or @email mentions someone, @me yourself")
+	a.onSubmit = func(text string) { a.postComment(item, text) }
+}
+
+// postComment resolves the @mentions in text and adds it to the work
+// item's discussion.
+func (a *App) postComment(item domain.WorkItem, text string) {
+	if text = strings.TrimSpace(text); text == "" {
+		a.setStatus("Comment not posted: it was empty")
+		return
+	}
+	a.setStatus(fmt.Sprintf("Posting comment on #%d...", item.ID))
+	svc, ctx := a.workItemService(item), a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		body, err := api.CommentHTML(ctx, svc, text)
+		if err == nil {
+			_, err = svc.AddWorkItemComment(ctx, item.ID, body)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				a.setStatus("⚠ Comment not posted: " + api.Explain(err))
+			}
+			return
+		}
+		a.setStatus(fmt.Sprintf("✓ Commented on #%d", item.ID))
+		a.loadComments(item, true)
+	}()
+}
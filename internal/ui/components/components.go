@@ -3,8 +3,10 @@ package components
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/user/apo/internal/ui/terminal"
 )
@@ -86,6 +88,9 @@ type StatusBar struct {
 	messageTime time.Time
 	lastRefresh time.Time
 	helpText    string
+	rateLimit   string
+	staleSince  time.Time
+	offline     bool
 }
 
 // NewStatusBar creates a new status bar.
@@ -104,6 +109,18 @@ func (s *StatusBar) SetLastRefresh(t time.Time) {
 	s.lastRefresh = t
 }
 
+// SetStaleSince marks the displayed data as a snapshot saved at t, and
+// whether Azure DevOps is currently unreachable. A zero t clears the mark.
+func (s *StatusBar) SetStaleSince(t time.Time, offline bool) {
+	s.staleSince = t
+	s.offline = offline
+}
+
+// SetRateLimit shows the remaining API rate-limit budget.
+func (s *StatusBar) SetRateLimit(remaining, limit int) {
+	s.rateLimit = fmt.Sprintf("API budget: %d/%d", remaining, limit)
+}
+
 // SetHelp sets the help text.
 func (s *StatusBar) SetHelp(text string) {
 	s.helpText = text
@@ -121,16 +138,47 @@ func (s *StatusBar) Render(row, width int) {
 		fmt.Print(terminal.Style(" "+s.message+" ", terminal.FgYellow))
 	}
 
-	if !s.lastRefresh.IsZero() {
-		refresh := fmt.Sprintf("Last refresh: %s", s.lastRefresh.Format("15:04:05"))
-		s.term.MoveTo(row, width-len(refresh)-2)
-		fmt.Print(terminal.Style(refresh, terminal.Dim))
+	right := ""
+	if s.rateLimit != "" {
+		right = s.rateLimit
+	}
+	switch {
+	case !s.staleSince.IsZero():
+		if right != "" {
+			right += " │ "
+		}
+		if s.offline {
+			right += "Offline · "
+		}
+		right += fmt.Sprintf("Data from %s ago", FormatAge(time.Since(s.staleSince)))
+	case !s.lastRefresh.IsZero():
+		if right != "" {
+			right += " │ "
+		}
+		right += fmt.Sprintf("Last refresh: %s", s.lastRefresh.Format("15:04:05"))
+	}
+	if right != "" {
+		s.term.MoveTo(row, width-len([]rune(right))-2)
+		fmt.Print(terminal.Style(right, terminal.Dim))
 	}
 
 	s.term.MoveTo(row+1, 1)
 	fmt.Print(terminal.Style(terminal.Pad(s.helpText, width), terminal.BgBlue, terminal.FgWhite))
 }
 
+// FormatAge formats a duration coarsely, e.g. "45s", "12m", "3h" or "2d".
+func FormatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
 // Input is a text input component.
 type Input struct {
 	term   *terminal.Terminal
@@ -178,6 +226,46 @@ func (i *Input) Render(row, col, width int) {
 	}
 }
 
+// LoadState is the load state of the data behind a component.
+type LoadState struct {
+	Loading bool
+	Error   string // short, user-facing description of the last failure
+}
+
+// Render draws the state at the cursor as a short label, e.g.
+// "⏳ loading" or "⚠ 403 Forbidden". It draws nothing when ready.
+func (s LoadState) Render() {
+	switch {
+	case s.Loading:
+		fmt.Print(terminal.Style("  ⏳ loading", terminal.Dim))
+	case s.Error != "":
+		fmt.Print(terminal.Style("  ⚠ "+s.Error, terminal.FgRed))
+	}
+}
+
+// Highlight marks a row that changed since the previous refresh.
+type Highlight int
+
+const (
+	HighlightNone Highlight = iota
+	HighlightNew
+	HighlightChanged
+	HighlightAlert // a change that needs attention, e.g. a failed build
+)
+
+// Render draws the highlight's tag at the cursor, e.g. "● new". It draws
+// nothing for HighlightNone.
+func (h Highlight) Render() {
+	switch h {
+	case HighlightNew:
+		fmt.Print(terminal.Style(" ● new", terminal.Bold, terminal.FgGreen))
+	case HighlightChanged:
+		fmt.Print(terminal.Style(" ● updated", terminal.Bold, terminal.FgCyan))
+	case HighlightAlert:
+		fmt.Print(terminal.Style(" ● attention", terminal.Bold, terminal.FgRed))
+	}
+}
+
 // ListItem represents an item in a list.
 type ListItem struct {
 	ID, Icon, Label, Sublabel string
@@ -186,15 +274,17 @@ type ListItem struct {
 
 // List is a scrollable list component.
 type List struct {
-	term          *terminal.Terminal
-	title         string
-	items         []ListItem
-	filtered      []int
-	selected      int
-	scroll        int
-	height        int
-	filterMode    bool
-	filterQuery   string
+	term        *terminal.Terminal
+	title       string
+	items       []ListItem
+	filtered    []int
+	selected    int
+	scroll      int
+	height      int
+	filterMode  bool
+	filterQuery string
+	state       LoadState
+	highlights  map[string]Highlight
 }
 
 // NewList creates a new list.
@@ -202,12 +292,39 @@ func NewList(term *terminal.Terminal, title string) *List {
 	return &List{term: term, title: title}
 }
 
-// SetItems sets the list items.
+// SetItems sets the list items. The filter is re-applied and the selection
+// stays on the same item when it is still present, so periodic refreshes do
+// not move the cursor.
 func (l *List) SetItems(items []ListItem) {
+	var selectedID string
+	if item := l.SelectedItem(); item != nil {
+		selectedID = item.ID
+	}
+
 	l.items = items
-	l.ClearFilter()
+	l.applyFilter()
+	l.selected = 0
+	if selectedID != "" {
+		for i, idx := range l.activeIndices() {
+			if l.items[idx].ID == selectedID {
+				l.selected = i
+				break
+			}
+		}
+	}
+	l.adjustScroll()
 }
 
+// SetLoadState sets the load state shown next to the title.
+func (l *List) SetLoadState(state LoadState) { l.state = state }
+
+// SetTitle replaces the list's title.
+func (l *List) SetTitle(title string) { l.title = title }
+
+// SetHighlights marks rows by ListItem.ID. Rows not in the map are drawn
+// normally.
+func (l *List) SetHighlights(highlights map[string]Highlight) { l.highlights = highlights }
+
 // SelectedIndex returns the selected index.
 func (l *List) SelectedIndex() int { return l.selected }
 
@@ -333,6 +450,7 @@ func (l *List) Render(startRow, startCol, width, height int) {
 		titleText = fmt.Sprintf("%s (%d)", l.title, len(l.items))
 	}
 	fmt.Print(terminal.Style(titleText, terminal.Bold, terminal.FgYellow))
+	l.state.Render()
 
 	if l.filterMode {
 		l.term.MoveTo(startRow, width-30)
@@ -360,19 +478,35 @@ func (l *List) Render(startRow, startCol, width, height int) {
 		item := l.items[idx]
 
 		l.term.MoveTo(row, startCol)
-		line := item.Icon + " " + terminal.Truncate(item.Label, width-startCol-10)
+		h := l.highlights[item.ID]
+		labelWidth := width - startCol - 10
+		if h != HighlightNone {
+			labelWidth -= 12
+		}
+		line := item.Icon + " " + terminal.Truncate(item.Label, labelWidth)
 
 		if i == l.selected {
 			fmt.Print(terminal.Style(terminal.Pad(line, width-startCol-1), terminal.Reverse))
 		} else {
 			fmt.Print(line)
 		}
+		if h != HighlightNone {
+			l.term.MoveTo(row, width-14)
+			h.Render()
+		}
 		row++
 	}
 
 	if len(l.items) == 0 {
 		l.term.MoveTo(row, startCol+2)
-		fmt.Print(terminal.Style("No items", terminal.Dim))
+		switch {
+		case l.state.Loading:
+			fmt.Print(terminal.Style("Loading...", terminal.Dim))
+		case l.state.Error != "":
+			fmt.Print(terminal.Style("Could not load: "+l.state.Error, terminal.FgRed))
+		default:
+			fmt.Print(terminal.Style("No items", terminal.Dim))
+		}
 	}
 
 	if l.scroll > 0 {
@@ -384,3 +518,497 @@ func (l *List) Render(startRow, startCol, width, height int) {
 		fmt.Print(terminal.Style("▼", terminal.FgYellow))
 	}
 }
+
+// PickerItem is an entry in a Picker.
+type PickerItem struct {
+	Value, Label, Detail string
+}
+
+// Picker is a modal, searchable list drawn over the current view. Typing
+// narrows the list to items whose label fuzzily matches the query.
+type Picker struct {
+	term     *terminal.Terminal
+	title    string
+	items    []PickerItem
+	matches  []int
+	query    string
+	selected int
+	open     bool
+}
+
+// NewPicker creates a closed picker.
+func NewPicker(term *terminal.Terminal) *Picker {
+	return &Picker{term: term}
+}
+
+// Open shows the picker with items, selecting the one whose value is
+// current.
+func (p *Picker) Open(title string, items []PickerItem, current string) {
+	p.title = title
+	p.items = items
+	p.query = ""
+	p.match()
+	for i, idx := range p.matches {
+		if items[idx].Value == current {
+			p.selected = i
+		}
+	}
+	p.open = true
+}
+
+// Close hides the picker.
+func (p *Picker) Close() { p.open = false }
+
+// IsOpen returns whether the picker is shown.
+func (p *Picker) IsOpen() bool { return p.open }
+
+// InsertChar adds a character to the search query.
+func (p *Picker) InsertChar(c rune) {
+	p.query += string(c)
+	p.match()
+}
+
+// Backspace removes the last character of the search query.
+func (p *Picker) Backspace() {
+	if len(p.query) > 0 {
+		runes := []rune(p.query)
+		p.query = string(runes[:len(runes)-1])
+		p.match()
+	}
+}
+
+// MoveUp moves selection up.
+func (p *Picker) MoveUp() {
+	if p.selected > 0 {
+		p.selected--
+	}
+}
+
+// MoveDown moves selection down.
+func (p *Picker) MoveDown() {
+	if p.selected < len(p.matches)-1 {
+		p.selected++
+	}
+}
+
+// Selected returns the selected item, or nil when nothing matches.
+func (p *Picker) Selected() *PickerItem {
+	if p.selected >= 0 && p.selected < len(p.matches) {
+		return &p.items[p.matches[p.selected]]
+	}
+	return nil
+}
+
+// match recomputes the items matching the query, best first.
+func (p *Picker) match() {
+	type scored struct{ idx, score int }
+	var found []scored
+	for i, item := range p.items {
+		if score, ok := fuzzyScore(p.query, item.Label); ok {
+			found = append(found, scored{i, score})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+
+	p.matches = p.matches[:0]
+	for _, f := range found {
+		p.matches = append(p.matches, f.idx)
+	}
+	p.selected = 0
+}
+
+// fuzzyScore reports whether the characters of query appear in s in order,
+// ignoring case. Matches are scored higher when characters are consecutive
+// or start a word, so "pw" ranks "platform-web" above "api-gateway".
+func fuzzyScore(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	score, qi, prev := 0, 0, -2
+	runes := []rune(strings.ToLower(s))
+	for i, r := range runes {
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 3
+		}
+		if i == 0 || strings.ContainsRune(" -_./", runes[i-1]) {
+			score += 2
+		}
+		prev = i
+		qi++
+		if qi == len(q) {
+			return score - len(runes)/10, true
+		}
+	}
+	return 0, false
+}
+
+// Render draws the picker as a box centered in the screen area.
+func (p *Picker) Render(width, height int) {
+	if !p.open {
+		return
+	}
+	boxWidth := width / 2
+	if boxWidth < 40 {
+		boxWidth = 40
+	}
+	rows := len(p.matches)
+	if rows > height-8 {
+		rows = height - 8
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	top := (height-rows-6)/2 + 1
+	left := (width-boxWidth)/2 + 1
+	inner := boxWidth - 2
+
+	start := 0
+	if p.selected >= rows {
+		start = p.selected - rows + 1
+	}
+	labelWidth := 0
+	for _, item := range p.items {
+		if n := utf8.RuneCountInString(item.Label); n > labelWidth {
+			labelWidth = n
+		}
+	}
+
+	p.term.MoveTo(top, left)
+	fmt.Print(terminal.Style("┌"+PadRunes(" "+p.title+" ", inner)+"┐", terminal.Bold, terminal.FgCyan))
+	p.term.MoveTo(top+1, left)
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	fmt.Print(PadRunes(" > "+p.query, inner))
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	p.term.MoveTo(top+2, left)
+	fmt.Print(terminal.Style("├"+strings.Repeat("─", inner)+"┤", terminal.FgCyan))
+
+	for i := 0; i < rows; i++ {
+		line := terminal.Pad("", inner)
+		if start+i < len(p.matches) {
+			item := p.items[p.matches[start+i]]
+			line = PadRunes(" "+PadRunes(item.Label, labelWidth)+"  "+item.Detail, inner)
+		} else if i == 0 {
+			line = terminal.Pad(" No matches", inner)
+		}
+		p.term.MoveTo(top+3+i, left)
+		fmt.Print(terminal.Style("│", terminal.FgCyan))
+		if start+i == p.selected && start+i < len(p.matches) {
+			fmt.Print(terminal.Style(line, terminal.Reverse))
+		} else {
+			fmt.Print(line)
+		}
+		fmt.Print(terminal.Style("│", terminal.FgCyan))
+	}
+	p.term.MoveTo(top+3+rows, left)
+	fmt.Print(terminal.Style("│"+terminal.Pad(" [Enter] Select   [Esc] Cancel", inner)+"│", terminal.FgCyan))
+	p.term.MoveTo(top+4+rows, left)
+	fmt.Print(terminal.Style("└"+strings.Repeat("─", inner)+"┘", terminal.FgCyan))
+}
+
+// Prompt is a modal, single-line text box drawn over the current view.
+type Prompt struct {
+	term  *terminal.Terminal
+	title string
+	hint  string
+	value []rune
+	open  bool
+}
+
+// NewPrompt creates a closed prompt.
+func NewPrompt(term *terminal.Terminal) *Prompt {
+	return &Prompt{term: term}
+}
+
+// Open shows the prompt with an initial value and a hint line below it.
+func (p *Prompt) Open(title, value, hint string) {
+	p.title = title
+	p.value = []rune(value)
+	p.hint = hint
+	p.open = true
+}
+
+// Close hides the prompt.
+func (p *Prompt) Close() { p.open = false }
+
+// IsOpen returns whether the prompt is shown.
+func (p *Prompt) IsOpen() bool { return p.open }
+
+// Value returns the text entered.
+func (p *Prompt) Value() string { return string(p.value) }
+
+// InsertChar adds a character to the text.
+func (p *Prompt) InsertChar(c rune) { p.value = append(p.value, c) }
+
+// Backspace removes the last character of the text.
+func (p *Prompt) Backspace() {
+	if len(p.value) > 0 {
+		p.value = p.value[:len(p.value)-1]
+	}
+}
+
+// Render draws the prompt as a box centered in the screen area. Text wider
+// than the box scrolls so the end stays visible.
+func (p *Prompt) Render(width, height int) {
+	if !p.open {
+		return
+	}
+	boxWidth := width * 2 / 3
+	if boxWidth < 40 {
+		boxWidth = 40
+	}
+	top := (height-5)/2 + 1
+	left := (width-boxWidth)/2 + 1
+	inner := boxWidth - 2
+
+	text := p.value
+	if visible := inner - 4; len(text) > visible {
+		text = text[len(text)-visible:]
+	}
+
+	p.term.MoveTo(top, left)
+	fmt.Print(terminal.Style("┌"+terminal.Pad(" "+p.title+" ", inner)+"┐", terminal.Bold, terminal.FgCyan))
+	p.term.MoveTo(top+1, left)
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	fmt.Print(" > " + string(text) + "█" + strings.Repeat(" ", max(inner-4-len(text), 0)))
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	p.term.MoveTo(top+2, left)
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	fmt.Print(terminal.Style(terminal.Pad(" "+p.hint, inner), terminal.Dim))
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	p.term.MoveTo(top+3, left)
+	fmt.Print(terminal.Style("│"+terminal.Pad(" [Enter] Save   [Esc] Cancel", inner)+"│", terminal.FgCyan))
+	p.term.MoveTo(top+4, left)
+	fmt.Print(terminal.Style("└"+strings.Repeat("─", inner)+"┘", terminal.FgCyan))
+}
+
+// FormField is a field of a Form. A field with Choices is set by picking
+// one of them rather than by typing.
+type FormField struct {
+	Name     string // identifies the field to the caller
+	Label    string
+	Value    string
+	Required bool
+	Choices  []PickerItem
+	Hint     string // shown while the field has focus
+}
+
+// Form is a modal set of fields drawn over the current view, followed by a
+// submit button.
+type Form struct {
+	term   *terminal.Terminal
+	title  string
+	submit string
+	fields []FormField
+	focus  int // index into fields, or len(fields) for the submit button
+	err    string
+	open   bool
+}
+
+// NewForm creates a closed form.
+func NewForm(term *terminal.Terminal) *Form {
+	return &Form{term: term}
+}
+
+// Open shows the form with fields and a submit button labeled submit,
+// focusing the first field.
+func (f *Form) Open(title, submit string, fields []FormField) {
+	f.title = title
+	f.submit = submit
+	f.fields = fields
+	f.focus = 0
+	f.err = ""
+	f.open = true
+}
+
+// Close hides the form.
+func (f *Form) Close() { f.open = false }
+
+// IsOpen returns whether the form is shown.
+func (f *Form) IsOpen() bool { return f.open }
+
+// Field returns the field with the given name, or nil.
+func (f *Form) Field(name string) *FormField {
+	for i := range f.fields {
+		if f.fields[i].Name == name {
+			return &f.fields[i]
+		}
+	}
+	return nil
+}
+
+// Fields returns a copy of the fields, as edited so far.
+func (f *Form) Fields() []FormField {
+	return append([]FormField(nil), f.fields...)
+}
+
+// Values returns the value of each field by name.
+func (f *Form) Values() map[string]string {
+	values := make(map[string]string, len(f.fields))
+	for _, field := range f.fields {
+		values[field.Name] = field.Value
+	}
+	return values
+}
+
+// Focused returns the field with focus, or nil when the submit button has
+// it.
+func (f *Form) Focused() *FormField {
+	if f.focus < len(f.fields) {
+		return &f.fields[f.focus]
+	}
+	return nil
+}
+
+// Focus moves focus to the field with the given name.
+func (f *Form) Focus(name string) {
+	for i := range f.fields {
+		if f.fields[i].Name == name {
+			f.focus = i
+		}
+	}
+}
+
+// MoveUp moves focus to the previous field.
+func (f *Form) MoveUp() {
+	if f.focus > 0 {
+		f.focus--
+	}
+}
+
+// MoveDown moves focus to the next field or the submit button.
+func (f *Form) MoveDown() {
+	if f.focus < len(f.fields) {
+		f.focus++
+	}
+}
+
+// InsertChar adds a character to the focused field, unless it is a choice.
+func (f *Form) InsertChar(c rune) {
+	if field := f.Focused(); field != nil && field.Choices == nil {
+		field.Value += string(c)
+	}
+}
+
+// Backspace removes the last character of the focused field, unless it is
+// a choice.
+func (f *Form) Backspace() {
+	if field := f.Focused(); field != nil && field.Choices == nil {
+		if r := []rune(field.Value); len(r) > 0 {
+			field.Value = string(r[:len(r)-1])
+		}
+	}
+}
+
+// SetError shows a message below the fields until the next Open, or
+// clears it when msg is "".
+func (f *Form) SetError(msg string) { f.err = msg }
+
+// Render draws the form as a box centered in the screen area.
+func (f *Form) Render(width, height int) {
+	if !f.open {
+		return
+	}
+	boxWidth := width * 2 / 3
+	if boxWidth < 50 {
+		boxWidth = 50
+	}
+	top := (height-len(f.fields)-7)/2 + 1
+	left := (width-boxWidth)/2 + 1
+	inner := boxWidth - 2
+
+	labelWidth := 0
+	for _, field := range f.fields {
+		if n := utf8.RuneCountInString(field.Label) + 1; n > labelWidth {
+			labelWidth = n
+		}
+	}
+	valueWidth := inner - labelWidth - 6
+
+	line := func(row int, text string, styles ...string) {
+		f.term.MoveTo(row, left)
+		fmt.Print(terminal.Style("│", terminal.FgCyan))
+		fmt.Print(terminal.Style(PadRunes(text, inner), styles...))
+		fmt.Print(terminal.Style("│", terminal.FgCyan))
+	}
+
+	f.term.MoveTo(top, left)
+	fmt.Print(terminal.Style("┌"+PadRunes(" "+f.title+" ", inner)+"┐", terminal.Bold, terminal.FgCyan))
+	for i, field := range f.fields {
+		label := field.Label
+		if field.Required {
+			label += "*"
+		}
+		focused := i == f.focus
+		value := []rune(field.Value)
+		if len(value) > valueWidth {
+			if focused && field.Choices == nil {
+				value = value[len(value)-valueWidth:]
+			} else {
+				value = append(value[:valueWidth-3], []rune("...")...)
+			}
+		}
+		text := " " + PadRunes(label, labelWidth) + "  "
+		switch {
+		case focused && field.Choices == nil:
+			text += string(value) + "█"
+		case field.Choices != nil:
+			text += string(value) + " ▾"
+		default:
+			text += string(value)
+		}
+		text = PadRunes(text, inner)
+
+		f.term.MoveTo(top+1+i, left)
+		fmt.Print(terminal.Style("│", terminal.FgCyan))
+		if focused {
+			fmt.Print(terminal.Style(text, terminal.Reverse))
+		} else {
+			fmt.Print(text)
+		}
+		fmt.Print(terminal.Style("│", terminal.FgCyan))
+	}
+
+	row := top + 1 + len(f.fields)
+	line(row, "")
+	switch {
+	case f.err != "":
+		line(row+1, " "+f.err, terminal.FgRed)
+	case f.Focused() != nil:
+		line(row+1, " "+f.Focused().Hint, terminal.Dim)
+	default:
+		line(row+1, "")
+	}
+	button := "[ " + f.submit + " ]"
+	f.term.MoveTo(row+2, left)
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	fmt.Print(" ")
+	if f.focus == len(f.fields) {
+		fmt.Print(terminal.Style(button, terminal.Reverse, terminal.Bold))
+	} else {
+		fmt.Print(terminal.Style(button, terminal.Bold))
+	}
+	fmt.Print(strings.Repeat(" ", max(inner-1-len(button), 0)))
+	fmt.Print(terminal.Style("│", terminal.FgCyan))
+	f.term.MoveTo(row+3, left)
+	fmt.Print(terminal.Style("│"+PadRunes(" [↑↓/Tab] Move   [Enter] Pick/Next   [Esc] Cancel", inner)+"│", terminal.FgCyan))
+	f.term.MoveTo(row+4, left)
+	fmt.Print(terminal.Style("└"+strings.Repeat("─", inner)+"┘", terminal.FgCyan))
+}
+
+// PadRunes pads or cuts s to width characters. Unlike terminal.Pad it
+// counts runes, so symbols such as "▾" take one column.
+func PadRunes(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(r) >= width {
+		return string(r[:width])
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
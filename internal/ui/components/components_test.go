@@ -0,0 +1,78 @@
+package components
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, s string
+		ok       bool
+	}{
+		{"", "anything", true},
+		{"pw", "platform-web", true},
+		{"PW", "platform-web", true},
+		{"web", "Platform-Web", true},
+		{"pwx", "platform-web", false},
+		{"wp", "platform-web", false},
+		{"gatewayx", "api-gateway", false},
+		{"é", "Café", true},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.query, tt.s); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.s, ok, tt.ok)
+		}
+	}
+}
+
+func TestPickerRanking(t *testing.T) {
+	tests := []struct {
+		query string
+		items []string
+		want  []string
+	}{
+		// Word starts beat scattered letters.
+		{"pw", []string{"api-gateway", "platform-web"}, []string{"platform-web", "api-gateway"}},
+		// Consecutive letters beat letters split across words.
+		{"api", []string{"a-p-i", "api"}, []string{"api", "a-p-i"}},
+		// Shorter labels win a tie.
+		{"web", []string{"web-frontend-legacy-2019", "web"}, []string{"web", "web-frontend-legacy-2019"}},
+		// Equal scores keep their order, and non-matches drop out.
+		{"", []string{"b", "a", "c"}, []string{"b", "a", "c"}},
+		{"zz", []string{"a", "b"}, nil},
+	}
+	for _, tt := range tests {
+		p := &Picker{query: tt.query}
+		for _, label := range tt.items {
+			p.items = append(p.items, PickerItem{Value: label, Label: label})
+		}
+		p.match()
+		var got []string
+		for _, i := range p.matches {
+			got = append(got, p.items[i].Label)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("query %q ranks %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPadRunes(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 3, "abc"},
+		{"héllo", 3, "hél"},
+		{"abc", 0, ""},
+		{"abc", -4, ""},
+	}
+	for _, tt := range tests {
+		if got := PadRunes(tt.s, tt.width); got != tt.want {
+			t.Errorf("PadRunes(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/components"
+	"github.com/user/apo/internal/ui/terminal"
+)
+
+// Names of the create form's fields that are not work item fields.
+const (
+	formType   = "type"
+	formParent = "parent"
+)
+
+// defaultWorkItemType is preselected in the create form when the process
+// has it.
+const defaultWorkItemType = "Task"
+
+// openCreateForm loads the process's work item types, areas and iterations
+// in the background and shows the form for a new work item.
+func (a *App) openCreateForm() {
+	a.setStatus("Loading work item types...")
+	svc, ctx := a.service(), a.ctx
+	title := "New work item in " + a.config.Project
+
+	go func() {
+		defer a.requestRedraw()
+		types, err := svc.WorkItemTypes(ctx)
+		var areas, iterations []string
+		if err == nil {
+			areas, err = svc.AreaPaths(ctx)
+		}
+		if err == nil {
+			iterations, err = svc.IterationPaths(ctx)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				a.setStatus("⚠ " + api.Explain(err))
+			}
+			return
+		}
+		if len(types) == 0 {
+			a.setStatus("⚠ The process has no work item types that can be created")
+			return
+		}
+
+		typeChoices := make([]components.PickerItem, len(types))
+		typ := types[0].Name
+		for i, t := range types {
+			typeChoices[i] = components.PickerItem{Value: t.Name, Label: t.Name, Detail: terminal.Truncate(t.Description, 40)}
+			if t.Name == defaultWorkItemType {
+				typ = t.Name
+			}
+		}
+		fields := []components.FormField{
+			{Name: formType, Label: "Type", Value: typ, Required: true, Choices: typeChoices},
+			{Name: "System.Title", Label: "Title"},
+			{Name: "System.Description", Label: "Description"},
+			{Name: "System.AssignedTo", Label: "Assigned To", Hint: "Name or email, @me for yourself, empty for nobody"},
+			{Name: "System.AreaPath", Label: "Area", Value: first(areas), Choices: pathChoices(areas)},
+			{Name: "System.IterationPath", Label: "Iteration", Value: first(iterations), Choices: pathChoices(iterations)},
+			{Name: formParent, Label: "Parent", Hint: "ID of the parent work item, empty for none"},
+		}
+
+		a.post(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.form.Open(title, "Create", fields)
+			a.form.Focus("System.Title")
+			markRequired(a.form, types)
+			a.onFormChange = func(string) { markRequired(a.form, types) }
+			a.onFormSubmit = func(values map[string]string) { a.createWorkItem(title, types, values) }
+			a.statusBar.SetMessage("")
+		})
+	}()
+}
+
+// createWorkItem checks the form against the chosen type and creates the
+// work item in the background, opening it on success. On failure the form
+// is shown again with the error.
+func (a *App) createWorkItem(title string, types []domain.WorkItemType, values map[string]string) {
+	var typ domain.WorkItemType
+	for _, t := range types {
+		if t.Name == values[formType] {
+			typ = t
+		}
+	}
+	fields := map[string]interface{}{}
+	for name, value := range values {
+		if value = strings.TrimSpace(value); value != "" && name != formType && name != formParent {
+			fields[name] = value
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	parent := 0
+	if p := strings.TrimSpace(values[formParent]); p != "" {
+		id, err := strconv.Atoi(strings.TrimPrefix(p, "#"))
+		if err != nil || id <= 0 {
+			a.form.Focus(formParent)
+			a.form.SetError("⚠ Parent must be a work item ID")
+			return
+		}
+		parent = id
+	}
+	if missing := typ.MissingFields(fields); len(missing) > 0 {
+		for _, f := range typ.Fields {
+			if f.Name == missing[0] {
+				a.form.Focus(f.ReferenceName)
+			}
+		}
+		a.form.SetError(fmt.Sprintf("⚠ A %s needs %s", typ.Name, strings.Join(missing, ", ")))
+		return
+	}
+
+	form := a.form.Fields()
+	a.form.Close()
+	a.statusBar.SetMessage(fmt.Sprintf("Creating %s...", typ.Name))
+	svc, ctx := a.client, a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		var err error
+		if assignee, ok := fields["System.AssignedTo"].(string); ok {
+			fields["System.AssignedTo"], err = api.ResolveMe(ctx, svc, assignee)
+		}
+		var item domain.WorkItem
+		if err == nil {
+			item, err = svc.CreateWorkItem(ctx, typ.Name, fields, parent)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			a.post(func() {
+				a.mu.Lock()
+				defer a.mu.Unlock()
+				a.form.Open(title, "Create", form)
+				markRequired(a.form, types)
+				a.form.SetError("⚠ " + api.Explain(err))
+				a.statusBar.SetMessage("")
+			})
+			return
+		}
+
+		a.setStatus(fmt.Sprintf("✓ Created %s #%d", typ.Name, item.ID))
+		a.post(func() {
+			a.showWorkItemDetail(&item)
+			a.startRefresh(false)
+		})
+	}()
+}
+
+// handleFormKey handles input while the form is open. Enter picks a value
+// for a choice field, moves on from a text field, and submits on the
+// button.
+func (a *App) handleFormKey(key terminal.Key) {
+	a.mu.Lock()
+	var submitted map[string]string
+	switch key.Type {
+	case terminal.KeyEscape:
+		a.form.Close()
+	case terminal.KeyUp:
+		a.form.MoveUp()
+	case terminal.KeyDown, terminal.KeyTab:
+		a.form.MoveDown()
+	case terminal.KeyBackspace:
+		a.form.Backspace()
+	case terminal.KeyRune:
+		a.form.InsertChar(key.Rune)
+	case terminal.KeyEnter:
+		field := a.form.Focused()
+		switch {
+		case field == nil:
+			submitted = a.form.Values()
+		case field.Choices != nil:
+			name := field.Name
+			a.picker.Open(field.Label, field.Choices, field.Value)
+			a.onPick = func(value string) {
+				a.mu.Lock()
+				defer a.mu.Unlock()
+				a.form.Field(name).Value = value
+				a.form.SetError("")
+				a.form.MoveDown()
+				if a.onFormChange != nil {
+					a.onFormChange(name)
+				}
+			}
+		default:
+			a.form.MoveDown()
+		}
+	}
+	onSubmit := a.onFormSubmit
+	a.mu.Unlock()
+
+	if submitted != nil && onSubmit != nil {
+		onSubmit(submitted)
+	}
+}
+
+// markRequired marks the form fields the chosen type requires.
+func markRequired(form *components.Form, types []domain.WorkItemType) {
+	typ := form.Field(formType).Value
+	for _, t := range types {
+		if t.Name != typ {
+			continue
+		}
+		required := map[string]bool{}
+		for _, name := range t.MissingFields(nil) {
+			required[name] = true
+		}
+		for _, f := range t.Fields {
+			if field := form.Field(f.ReferenceName); field != nil {
+				field.Required = required[f.Name]
+			}
+		}
+	}
+}
+
+func pathChoices(paths []string) []components.PickerItem {
+	items := make([]components.PickerItem, len(paths))
+	for i, p := range paths {
+		items[i] = components.PickerItem{Value: p, Label: p}
+	}
+	return items
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,276 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/components"
+	"github.com/user/apo/internal/ui/terminal"
+)
+
+// editableFields are the work item fields the detail view can change, in
+// the order the edit menu lists them.
+var editableFields = []struct{ name, label string }{
+	{"System.State", "State"},
+	{"System.AssignedTo", "Assigned To"},
+	{"System.Title", "Title"},
+	{"System.IterationPath", "Iteration"},
+	{"System.AreaPath", "Area"},
+	{"Microsoft.VSTS.Common.Priority", "Priority"},
+}
+
+// priorities are the values of Microsoft.VSTS.Common.Priority.
+var priorities = []components.PickerItem{
+	{Value: "1", Label: "1", Detail: "Highest"},
+	{Value: "2", Label: "2", Detail: "High"},
+	{Value: "3", Label: "3", Detail: "Medium"},
+	{Value: "4", Label: "4", Detail: "Low"},
+}
+
+// editWorkItem edits a field of the work item in the detail view, or asks
+// which one when field is "".
+func (a *App) editWorkItem(field string) {
+	a.mu.RLock()
+	shown := a.workItemDetail.WorkItem()
+	a.mu.RUnlock()
+	if shown == nil {
+		return
+	}
+	item := *shown
+
+	switch field {
+	case "":
+		items := make([]components.PickerItem, len(editableFields))
+		for i, f := range editableFields {
+			items[i] = components.PickerItem{Value: f.name, Label: f.label, Detail: terminal.Truncate(item.GetField(f.name), 40)}
+		}
+		a.mu.Lock()
+		a.picker.Open(fmt.Sprintf("Edit #%d", item.ID), items, "")
+		a.onPick = a.editWorkItem
+		a.mu.Unlock()
+
+	case "System.State":
+		a.pickAsync(item, field, "Loading states...", func(svc api.Service) ([]components.PickerItem, error) {
+			states, err := svc.WorkItemStates(a.ctx, item.Type())
+			items := make([]components.PickerItem, len(states))
+			for i, s := range states {
+				items[i] = components.PickerItem{Value: s.Name, Label: s.Name, Detail: s.Category}
+			}
+			return items, err
+		})
+
+	case "System.IterationPath", "System.AreaPath":
+		list := api.Service.IterationPaths
+		if field == "System.AreaPath" {
+			list = api.Service.AreaPaths
+		}
+		a.pickAsync(item, field, "Loading paths...", func(svc api.Service) ([]components.PickerItem, error) {
+			paths, err := list(svc, a.ctx)
+			items := make([]components.PickerItem, len(paths))
+			for i, p := range paths {
+				items[i] = components.PickerItem{Value: p, Label: p}
+			}
+			return items, err
+		})
+
+	case "Microsoft.VSTS.Common.Priority":
+		a.mu.Lock()
+		a.picker.Open(fmt.Sprintf("Priority of #%d", item.ID), priorities, item.Priority())
+		a.onPick = func(value string) {
+			priority, _ := strconv.Atoi(value)
+			a.saveWorkItem(item, field, priority)
+		}
+		a.mu.Unlock()
+
+	case "System.Title":
+		a.mu.Lock()
+		a.prompt.Open(fmt.Sprintf("Title of #%d", item.ID), item.Title(), "")
+		a.onSubmit = func(value string) {
+			if value = strings.TrimSpace(value); value == "" {
+				a.setStatus("⚠ The title cannot be empty")
+				return
+			}
+			a.saveWorkItem(item, field, value)
+		}
+		a.mu.Unlock()
+
+	case "System.AssignedTo":
+		a.mu.Lock()
+		a.prompt.Open(fmt.Sprintf("Assign #%d to", item.ID), uniqueName(item, field),
+			"Name or email, @me for yourself, empty to unassign")
+		a.onSubmit = func(value string) { a.saveWorkItem(item, field, strings.TrimSpace(value)) }
+		a.mu.Unlock()
+	}
+}
+
+// pickAsync loads the allowed values of a field in the background and
+// offers them in the picker, saving the one picked.
+func (a *App) pickAsync(item domain.WorkItem, field, loading string, load func(api.Service) ([]components.PickerItem, error)) {
+	a.setStatus(loading)
+	svc, ctx := a.workItemService(item), a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		items, err := load(svc)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.setStatus("⚠ " + api.Explain(err))
+			}
+			return
+		}
+		a.post(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.picker.Open(fmt.Sprintf("%s of #%d", fieldLabel(field), item.ID), items, item.GetField(field))
+			a.onPick = func(value string) { a.saveWorkItem(item, field, value) }
+			a.statusBar.SetMessage("")
+		})
+	}()
+}
+
+// saveWorkItem sets one field of item, which must be as last loaded: the
+// update is rejected if someone else has changed the work item since, in
+// which case the latest revision is shown instead.
+func (a *App) saveWorkItem(item domain.WorkItem, field string, value interface{}) {
+	if fmt.Sprint(value) == item.GetField(field) || (field == "System.AssignedTo" && value == uniqueName(item, field)) {
+		a.setStatus(fmt.Sprintf("%s of #%d unchanged", fieldLabel(field), item.ID))
+		return
+	}
+	a.setStatus(fmt.Sprintf("Saving #%d...", item.ID))
+	svc, ctx := a.workItemService(item), a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		if field == "System.AssignedTo" {
+			name, err := api.ResolveMe(ctx, svc, fmt.Sprint(value))
+			if err != nil {
+				a.setStatus("⚠ Could not look up your identity: " + api.Explain(err))
+				return
+			}
+			value = name
+		}
+
+		updated, err := svc.UpdateWorkItem(ctx, item.ID, item.Rev, map[string]interface{}{field: value})
+		switch {
+		case api.IsConflict(err):
+			latest, lerr := svc.GetWorkItem(ctx, item.ID)
+			if lerr != nil {
+				a.setStatus(fmt.Sprintf("⚠ #%d was changed by someone else since you opened it, and reloading it failed: %s", item.ID, api.Explain(lerr)))
+				return
+			}
+			a.showUpdatedWorkItem(latest)
+			a.setStatus(fmt.Sprintf("⚠ #%d was changed by %s%s — reloaded; make your change again",
+				item.ID, orSomeone(latest.ChangedBy()), changedAt(latest)))
+		case err != nil:
+			if ctx.Err() == nil {
+				a.setStatus(fmt.Sprintf("⚠ Could not update #%d: %s", item.ID, api.Explain(err)))
+			}
+		default:
+			a.showUpdatedWorkItem(updated)
+			a.setStatus(fmt.Sprintf("✓ Updated %s of #%d", fieldLabel(field), item.ID))
+		}
+	}()
+}
+
+// showUpdatedWorkItem replaces a work item wherever it is shown.
+// The history is reloaded if it is showing, as the update added a revision.
+func (a *App) showUpdatedWorkItem(item domain.WorkItem) {
+	a.mu.Lock()
+	reload := false
+	if shown := a.workItemDetail.WorkItem(); shown != nil && shown.ID == item.ID {
+		a.workItemDetail.SetWorkItem(&item)
+		reload = a.workItemDetail.HistoryShown()
+	}
+	for i, existing := range a.workItems {
+		if existing.ID == item.ID {
+			a.workItems = append([]domain.WorkItem{}, a.workItems...)
+			a.workItems[i] = item
+			a.boards.SetWorkItems(a.workItems)
+			a.dashboard.SetData(a.workItems, a.builds, a.prList)
+			break
+		}
+	}
+	a.mu.Unlock()
+	if reload {
+		a.loadHistory(item)
+	}
+}
+
+// workItemService returns the client for the project a work item belongs
+// to, which differs from the current one in org-wide mode.
+func (a *App) workItemService(item domain.WorkItem) api.Service {
+	svc := a.service()
+	if p := item.TeamProject(); p != "" && !strings.EqualFold(p, a.config.Project) {
+		return svc.ForProject(p)
+	}
+	return svc
+}
+
+// handlePromptKey handles input while the prompt is open.
+func (a *App) handlePromptKey(key terminal.Key) {
+	a.mu.Lock()
+	var submitted *string
+	switch key.Type {
+	case terminal.KeyEscape:
+		a.prompt.Close()
+	case terminal.KeyBackspace:
+		a.prompt.Backspace()
+	case terminal.KeyRune:
+		a.prompt.InsertChar(key.Rune)
+	case terminal.KeyEnter:
+		value := a.prompt.Value()
+		submitted = &value
+		a.prompt.Close()
+	}
+	onSubmit := a.onSubmit
+	a.mu.Unlock()
+
+	if submitted != nil && onSubmit != nil {
+		onSubmit(*submitted)
+	}
+}
+
+func fieldLabel(field string) string {
+	for _, f := range editableFields {
+		if f.name == field {
+			return f.label
+		}
+	}
+	return field
+}
+
+// uniqueName returns the unique name (usually the email) of an identity
+// field, falling back to its display name.
+func uniqueName(item domain.WorkItem, field string) string {
+	if identity, ok := item.Fields[field].(map[string]interface{}); ok {
+		if name, ok := identity["uniqueName"].(string); ok && name != "" {
+			return name
+		}
+	}
+	return item.GetField(field)
+}
+
+func orSomeone(name string) string {
+	if name == "" {
+		return "someone else"
+	}
+	return name
+}
+
+// changedAt formats when a work item was last changed, as " at 14:02" today
+// or " on Oct 3 14:02" before.
+func changedAt(item domain.WorkItem) string {
+	t, err := time.Parse(time.RFC3339, item.GetField("System.ChangedDate"))
+	if err != nil {
+		return ""
+	}
+	t = t.Local()
+	if y, m, d := t.Date(); y == time.Now().Year() && m == time.Now().Month() && d == time.Now().Day() {
+		return " at " + t.Format("15:04")
+	}
+	return " on " + t.Format("Jan 2 15:04")
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/components"
+)
+
+// showHistory loads the history of the work item in the detail view. The
+// view calls it when the history tab is first opened.
+func (a *App) showHistory() {
+	a.mu.RLock()
+	shown := a.workItemDetail.WorkItem()
+	a.mu.RUnlock()
+	if shown != nil {
+		a.loadHistory(*shown)
+	}
+}
+
+// loadHistory loads the revisions of the work item in the detail view in
+// the background.
+func (a *App) loadHistory(item domain.WorkItem) {
+	a.mu.Lock()
+	a.workItemDetail.SetHistory(item.ID, a.historyShown(item.ID), components.LoadState{Loading: true})
+	a.mu.Unlock()
+	svc, ctx := a.workItemService(item), a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		updates, err := svc.WorkItemUpdates(ctx, item.ID)
+		if ctx.Err() != nil {
+			return
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		state := components.LoadState{}
+		if err != nil {
+			state.Error = api.Summary(err)
+			updates = a.historyShown(item.ID)
+		}
+		a.workItemDetail.SetHistory(item.ID, updates, state)
+	}()
+}
+
+// historyShown returns the revisions the detail view shows for work item
+// id, so a reload keeps them on screen until it completes.
+func (a *App) historyShown(id int) []domain.WorkItemUpdate {
+	if item := a.workItemDetail.WorkItem(); item != nil && item.ID == id {
+		return a.workItemDetail.History()
+	}
+	return nil
+}
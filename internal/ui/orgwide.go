@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"context"
+	"sort"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/config"
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/snapshot"
+)
+
+// orgWideBuilds caps how many builds org-wide mode keeps after merging the
+// most recent builds of each project.
+const orgWideBuilds = 50
+
+// orgWideSnapshot is the snapshot name used in org-wide mode. Azure DevOps
+// project names cannot start with an underscore, so it never collides.
+const orgWideSnapshot = "_all"
+
+// toggleOrgWide switches between the current project and org-wide mode, in
+// which work items, builds and pull requests are aggregated across projects.
+func (a *App) toggleOrgWide() {
+	a.mu.Lock()
+	a.orgWide = !a.orgWide
+	a.boards.SetShowProject(a.orgWide)
+	a.prs.SetShowProject(a.orgWide)
+	a.dashboard.SetShowProject(a.orgWide)
+	orgWide := a.orgWide
+	a.mu.Unlock()
+
+	a.reload()
+	if orgWide {
+		a.setStatus("Org-wide mode: aggregating across projects")
+	} else {
+		a.setStatus("Showing project " + a.config.Project)
+	}
+}
+
+func (a *App) isOrgWide() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.orgWide
+}
+
+// snapshotStore returns the snapshot store for what is being shown. It must
+// be called with a.mu held.
+func (a *App) snapshotStore() *snapshot.Store {
+	project := a.config.Project
+	if a.orgWide {
+		project = orgWideSnapshot
+	}
+	return snapshot.NewStore(config.GetSnapshotDir(), a.config.CollectionName(), project)
+}
+
+// aggregateProjects returns the projects org-wide mode covers: those
+// configured in org_projects, or else every project in the organization.
+func (a *App) aggregateProjects(ctx context.Context, svc api.Service) ([]string, error) {
+	a.mu.RLock()
+	projects := a.config.AggregateProjects()
+	a.mu.RUnlock()
+	if len(projects) > 0 {
+		return projects, nil
+	}
+	all, err := svc.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(all))
+	for i, p := range all {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+// fetchWorkItems returns the user's work items in the current project or,
+// in org-wide mode, in every aggregated project, most recently changed
+// first. Work items are not per project, so org-wide mode runs one query
+// limited to the aggregated projects rather than one query per project.
+func (a *App) fetchWorkItems(ctx context.Context) ([]domain.WorkItem, error) {
+	svc := a.service()
+	if !a.isOrgWide() {
+		return svc.GetMyWorkItems(ctx)
+	}
+	projects, err := a.aggregateProjects(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	return svc.QueryWorkItems(ctx, api.MyWorkItemsQuery(projects...))
+}
+
+// fetchBuilds returns recent builds in the current project or, in org-wide
+// mode, the most recent across every aggregated project.
+func (a *App) fetchBuilds(ctx context.Context) ([]domain.Build, error) {
+	svc := a.service()
+	if !a.isOrgWide() {
+		return svc.ListBuilds(ctx, "", "", 20)
+	}
+	projects, err := a.aggregateProjects(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	builds, err := api.AcrossProjects(ctx, svc, projects, func(ctx context.Context, s api.Service) ([]domain.Build, error) {
+		return s.ListBuilds(ctx, "", "", 20)
+	})
+	sort.SliceStable(builds, func(i, j int) bool {
+		return builds[i].QueueTime.After(builds[j].QueueTime)
+	})
+	if len(builds) > orgWideBuilds {
+		builds = builds[:orgWideBuilds]
+	}
+	return builds, err
+}
+
+// fetchPullRequests returns active pull requests in the current project or,
+// in org-wide mode, across every aggregated project, newest first.
+func (a *App) fetchPullRequests(ctx context.Context) ([]domain.PullRequest, error) {
+	svc := a.service()
+	if !a.isOrgWide() {
+		return svc.GetActivePullRequests(ctx, 0)
+	}
+	projects, err := a.aggregateProjects(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	prs, err := api.AcrossProjects(ctx, svc, projects, func(ctx context.Context, s api.Service) ([]domain.PullRequest, error) {
+		return s.GetActivePullRequests(ctx, 0)
+	})
+	sort.SliceStable(prs, func(i, j int) bool {
+		return prs[i].CreationDate.After(prs[j].CreationDate)
+	})
+	return prs, err
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/ui/components"
+)
+
+// myWorkItemsPick is the query picker entry that goes back to the user's
+// own work items. Saved query IDs are GUIDs, so it never collides.
+const myWorkItemsPick = "@me"
+
+// openQueryPicker loads the project's saved queries in the background and
+// offers them, together with the user's own work items, for Boards.
+func (a *App) openQueryPicker() {
+	a.setStatus("Loading saved queries...")
+	client, ctx := a.service(), a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		queries, err := client.ListQueries(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.setStatus("⚠ Queries: " + api.Explain(err))
+			}
+			return
+		}
+		items := []components.PickerItem{{Value: myWorkItemsPick, Label: "My work items", Detail: "assigned to me"}}
+		names := map[string]string{myWorkItemsPick: "My work items"}
+		for _, q := range queries {
+			items = append(items, components.PickerItem{Value: q.ID, Label: q.Name, Detail: q.Path})
+			names[q.ID] = q.Name
+		}
+
+		a.post(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			current := myWorkItemsPick
+			for _, q := range queries {
+				if q.Name == a.boards.Query() {
+					current = q.ID
+				}
+			}
+			a.picker.Open("Run saved query", items, current)
+			a.onPick = func(id string) { a.runSavedQuery(id, names[id]) }
+			a.statusBar.SetMessage("")
+		})
+	}()
+}
+
+// runSavedQuery shows a saved query's results in Boards, or the user's own
+// work items again for myWorkItemsPick.
+func (a *App) runSavedQuery(id, name string) {
+	if id == myWorkItemsPick {
+		a.clearSavedQuery()
+		return
+	}
+	a.setStatus("Running " + name + "...")
+	client, ctx := a.service(), a.ctx
+
+	go func() {
+		defer a.requestRedraw()
+		items, err := client.RunQuery(ctx, id)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.setStatus("⚠ " + name + ": " + api.Explain(err))
+			}
+			return
+		}
+		a.mu.Lock()
+		if a.client != client {
+			// Switched project or profile while the query ran.
+			a.mu.Unlock()
+			return
+		}
+		a.boards.SetQueryResults(name, items)
+		a.mu.Unlock()
+		a.setStatus(fmt.Sprintf("%s: %d work item(s) │ [s] Another query │ [Esc] My work items", name, len(items)))
+	}()
+}
+
+// clearSavedQuery goes back to the user's own work items in Boards.
+func (a *App) clearSavedQuery() {
+	a.mu.Lock()
+	a.boards.ClearQuery()
+	a.mu.Unlock()
+	a.setStatus("Showing my work items")
+}
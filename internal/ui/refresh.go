@@ -0,0 +1,287 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/snapshot"
+	"github.com/user/apo/internal/ui/components"
+	"github.com/user/apo/internal/ui/views"
+)
+
+// section identifies an independently loaded part of the dataset.
+type section string
+
+const (
+	sectionWorkItems    section = "Work items"
+	sectionBuilds       section = "Builds"
+	sectionPipelines    section = "Pipelines"
+	sectionRepositories section = "Repos"
+	sectionPullRequests section = "PRs"
+)
+
+var allSections = []section{
+	sectionWorkItems,
+	sectionBuilds,
+	sectionPipelines,
+	sectionRepositories,
+	sectionPullRequests,
+}
+
+// refreshData loads every section concurrently. Each section is shown as
+// soon as it arrives, and its view reports its own loading or error state.
+func (a *App) refreshData(ctx context.Context) {
+	var (
+		wg          sync.WaitGroup
+		errMu       sync.Mutex
+		firstErr    error
+		firstSec    section
+		unreachable bool
+	)
+	for _, sec := range allSections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.beginLoad(sec, false)
+			defer a.endLoad(sec)
+			err := a.refreshSection(ctx, sec, false)
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+			errMu.Lock()
+			defer errMu.Unlock()
+			if firstErr == nil {
+				firstErr, firstSec = err, sec
+			}
+			unreachable = unreachable || api.IsUnreachable(err)
+		}()
+	}
+	wg.Wait()
+
+	a.mu.Lock()
+	if ctx.Err() != nil {
+		a.mu.Unlock()
+		return
+	}
+	// Keep showing the previous data, marked stale, while offline.
+	if unreachable {
+		a.markOffline()
+	} else {
+		a.offline = false
+		a.staleSince = time.Time{}
+		a.lastRefresh = time.Now()
+		a.saveSnapshot()
+		a.statusBar.SetStaleSince(a.staleSince, a.offline)
+	}
+	a.statusBar.SetLastRefresh(a.lastRefresh)
+	if rl, ok := a.client.RateLimit(); ok {
+		a.statusBar.SetRateLimit(rl.Remaining, rl.Limit)
+	}
+	a.mu.Unlock()
+	defer a.requestRedraw()
+
+	if firstErr != nil {
+		a.setStatus(fmt.Sprintf("⚠ %s: %s", firstSec, api.Explain(firstErr)))
+		return
+	}
+	a.setStatus("Data refreshed")
+}
+
+// markOffline marks the data shown as stale because Azure DevOps cannot
+// be reached. It must be called with a.mu held.
+func (a *App) markOffline() {
+	a.offline = true
+	if a.staleSince.IsZero() {
+		a.staleSince = a.lastRefresh
+	}
+	a.statusBar.SetStaleSince(a.staleSince, a.offline)
+}
+
+// refreshSection loads one section and updates its load state. Background
+// refreshes do not show a loading indicator, so polling does not flicker.
+// A section the response cache answered because Azure DevOps could not be
+// reached is shown, but reported as unreachable so it is not taken for live
+// data.
+func (a *App) refreshSection(ctx context.Context, sec section, background bool) error {
+	if !background {
+		a.setSectionState(sec, components.LoadState{Loading: true})
+	}
+
+	ctx, stale := api.WithStaleness(ctx)
+	notes, err := a.loadSection(ctx, sec)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == nil {
+		err = stale.Err()
+	}
+	if err != nil {
+		a.setSectionState(sec, components.LoadState{Error: api.Summary(err)})
+	} else {
+		a.setSectionState(sec, components.LoadState{})
+	}
+	if len(notes) > 0 {
+		a.setStatus("🔔 " + notes[0])
+	}
+	a.requestRedraw()
+	return err
+}
+
+// loadSection fetches a section and shows it, highlighting rows that differ
+// from the previous live load. It returns notable changes for the status
+// bar. Results arriving after ctx is canceled are discarded. In org-wide
+// mode a section can load partially: what arrived is shown and the error
+// for the failed projects is returned.
+func (a *App) loadSection(ctx context.Context, sec section) ([]string, error) {
+	switch sec {
+	case sectionWorkItems:
+		items, err := a.fetchWorkItems(ctx)
+		if items == nil && err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err // e.g. the profile was switched meanwhile
+		}
+		var notes []string
+		if a.loaded[sec] {
+			var changes map[string]components.Highlight
+			changes, notes = diffWorkItems(a.workItems, items)
+			a.recordChanges(sec, changes)
+		}
+		a.workItems = items
+		a.boards.SetWorkItems(items)
+		a.dashboard.SetData(a.workItems, a.builds, a.prList)
+		a.markLoaded(sec)
+		return notes, err
+
+	case sectionBuilds:
+		builds, err := a.fetchBuilds(ctx)
+		if builds == nil && err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err // e.g. the profile was switched meanwhile
+		}
+		var notes []string
+		if a.loaded[sec] {
+			var changes map[string]components.Highlight
+			changes, notes = diffBuilds(a.builds, builds)
+			a.recordChanges(sec, changes)
+		}
+		a.builds = builds
+		a.dashboard.SetData(a.workItems, a.builds, a.prList)
+		a.markLoaded(sec)
+		return notes, err
+
+	case sectionPipelines:
+		pipelines, err := a.service().ListPipelines(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err // e.g. the profile was switched meanwhile
+		}
+		var notes []string
+		if a.loaded[sec] {
+			var changes map[string]components.Highlight
+			changes, notes = diffPipelines(a.pipelineList, pipelines)
+			a.recordChanges(sec, changes)
+		}
+		a.pipelineList = pipelines
+		a.pipelines.SetPipelines(pipelines)
+		a.markLoaded(sec)
+		return notes, nil
+
+	case sectionRepositories:
+		repos, err := a.service().ListRepositories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err // e.g. the profile was switched meanwhile
+		}
+		var notes []string
+		if a.loaded[sec] {
+			var changes map[string]components.Highlight
+			changes, notes = diffRepositories(a.repoList, repos)
+			a.recordChanges(sec, changes)
+		}
+		a.repoList = repos
+		a.repos.SetRepositories(repos)
+		a.markLoaded(sec)
+		return notes, nil
+
+	case sectionPullRequests:
+		prs, err := a.fetchPullRequests(ctx)
+		if prs == nil && err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err // e.g. the profile was switched meanwhile
+		}
+		var notes []string
+		if a.loaded[sec] {
+			var changes map[string]components.Highlight
+			changes, notes = diffPullRequests(a.prList, prs)
+			a.recordChanges(sec, changes)
+		}
+		a.prList = prs
+		a.prs.SetPullRequests(prs)
+		a.dashboard.SetData(a.workItems, a.builds, a.prList)
+		a.markLoaded(sec)
+		return notes, err
+	}
+	return nil, fmt.Errorf("unknown section %q", sec)
+}
+
+// markLoaded records a successful live load and re-applies highlights,
+// since setting a view's data resets them. It must be called with a.mu held.
+func (a *App) markLoaded(sec section) {
+	a.loaded[sec] = true
+	a.applyHighlights(sec)
+}
+
+// saveSnapshot persists the current dataset. It must be called with a.mu
+// held.
+func (a *App) saveSnapshot() {
+	a.snapshots.Save(&snapshot.Snapshot{
+		WorkItems:    a.workItems,
+		Builds:       a.builds,
+		Pipelines:    a.pipelineList,
+		Repositories: a.repoList,
+		PullRequests: a.prList,
+	})
+}
+
+// setSectionState shows a section's load state in the views displaying it.
+func (a *App) setSectionState(sec section, st components.LoadState) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch sec {
+	case sectionWorkItems:
+		a.boards.SetLoadState(st)
+		a.dashboard.SetLoadState(views.DashboardWorkItems, st)
+	case sectionBuilds:
+		a.dashboard.SetLoadState(views.DashboardBuilds, st)
+	case sectionPipelines:
+		a.pipelines.SetLoadState(st)
+	case sectionRepositories:
+		a.repos.SetLoadState(st)
+	case sectionPullRequests:
+		a.prs.SetLoadState(st)
+		a.dashboard.SetLoadState(views.DashboardPullRequests, st)
+	}
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/apo/internal/api"
+)
+
+// startPolling refreshes builds, pull requests and work items in the
+// background on the intervals in the auto_refresh config. Pipelines and
+// repositories change rarely and are only loaded on demand.
+func (a *App) startPolling() {
+	cfg := a.config.AutoRefresh
+	if cfg.Disabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.pollCancel = cancel
+	go a.poll(ctx, sectionBuilds, cfg.Builds())
+	go a.poll(ctx, sectionPullRequests, cfg.PullRequests())
+	go a.poll(ctx, sectionWorkItems, cfg.WorkItems())
+}
+
+// poll refreshes sec every interval until ctx is done. A tick is skipped
+// while the section is already loading, e.g. after the user pressed r. The
+// data is marked offline when Azure DevOps cannot be reached, and loaded
+// again in full once it can.
+func (a *App) poll(ctx context.Context, sec section, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !a.beginLoad(sec, true) {
+			continue
+		}
+		err := a.refreshSection(ctx, sec, true)
+		a.endLoad(sec)
+		if ctx.Err() != nil {
+			return
+		}
+
+		a.mu.Lock()
+		a.applyHighlights(sec) // expire old highlights even when nothing changed
+		if rl, ok := a.client.RateLimit(); ok {
+			a.statusBar.SetRateLimit(rl.Remaining, rl.Limit)
+		}
+		reconnected := err == nil && a.offline
+		if api.IsUnreachable(err) {
+			a.markOffline()
+		}
+		a.mu.Unlock()
+		if reconnected {
+			a.post(func() { a.startRefresh(false) })
+		}
+		a.requestRedraw()
+	}
+}
+
+// beginLoad records that sec is loading. With skipIfBusy it does nothing
+// and returns false when a load is already in flight.
+func (a *App) beginLoad(sec section, skipIfBusy bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if skipIfBusy && a.inflight[sec] > 0 {
+		return false
+	}
+	a.inflight[sec]++
+	return true
+}
+
+// endLoad records that a load of sec finished.
+func (a *App) endLoad(sec section) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inflight[sec]--
+}
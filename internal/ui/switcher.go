@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/user/apo/internal/agent"
+	"github.com/user/apo/internal/api"
+	"github.com/user/apo/internal/config"
+	"github.com/user/apo/internal/ui/components"
+	"github.com/user/apo/internal/ui/terminal"
+	"github.com/user/apo/internal/ui/views"
+	"github.com/user/apo/internal/ui/views/details"
+)
+
+// SetClientFactory sets how a client is created for another connection.
+// Without it, the profile switcher is unavailable.
+func (a *App) SetClientFactory(fn func(*config.Config) api.Service) {
+	a.newClient = fn
+}
+
+// service returns the client for the current connection. Background loads
+// use it rather than a.client, which changes when the profile is switched.
+func (a *App) service() api.Service {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.client
+}
+
+// openProfilePicker shows the configured profiles.
+func (a *App) openProfilePicker() {
+	if a.newClient == nil {
+		a.setStatus("Profile switching is not available")
+		return
+	}
+	var items []components.PickerItem
+	for _, name := range a.config.ProfileNames() {
+		p, _ := a.config.Profile(name)
+		items = append(items, components.PickerItem{
+			Value:  name,
+			Label:  name,
+			Detail: p.Organization + "/" + p.Project,
+		})
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.picker.Open("Switch profile", items, a.config.ProfileName())
+	a.onPick = a.switchProfile
+}
+
+// openProjectPicker loads the organization's projects in the background
+// and shows them.
+func (a *App) openProjectPicker() {
+	if a.newClient == nil {
+		a.setStatus("Project switching is not available")
+		return
+	}
+	a.setStatus("Loading projects...")
+	client, ctx := a.service(), a.ctx
+	org, current := a.config.CollectionName(), a.config.Project
+
+	go func() {
+		defer a.requestRedraw()
+		projects, err := client.ListProjects(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.setStatus("⚠ Projects: " + api.Explain(err))
+			}
+			return
+		}
+		items := make([]components.PickerItem, len(projects))
+		for i, p := range projects {
+			items[i] = components.PickerItem{
+				Value:  p.Name,
+				Label:  p.Name,
+				Detail: terminal.Truncate(p.Description, 40),
+			}
+		}
+
+		a.post(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.picker.Open("Switch project in "+org, items, current)
+			a.onPick = a.switchProject
+			a.statusBar.SetMessage("")
+		})
+	}()
+}
+
+// handlePickerKey handles input while the picker is open. Typing searches.
+func (a *App) handlePickerKey(key terminal.Key) {
+	a.mu.Lock()
+	var picked *components.PickerItem
+	switch key.Type {
+	case terminal.KeyEscape:
+		a.picker.Close()
+	case terminal.KeyUp:
+		a.picker.MoveUp()
+	case terminal.KeyDown:
+		a.picker.MoveDown()
+	case terminal.KeyBackspace:
+		a.picker.Backspace()
+	case terminal.KeyRune:
+		a.picker.InsertChar(key.Rune)
+	case terminal.KeyEnter:
+		picked = a.picker.Selected()
+		a.picker.Close()
+	}
+	onPick := a.onPick
+	a.mu.Unlock()
+
+	if picked != nil && onPick != nil {
+		onPick(picked.Value)
+	}
+}
+
+// switchProfile reconnects to another profile and reloads everything. The
+// config is changed under a.mu, as background loads read it.
+func (a *App) switchProfile(name string) {
+	if name == a.config.ProfileName() {
+		return
+	}
+	a.mu.Lock()
+	previous := a.config.ProfileName()
+	err := a.config.UseProfile(name)
+	if err == nil {
+		if err = a.config.ValidateWithProject(); err != nil {
+			a.config.UseProfile(previous)
+			err = fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	a.mu.Unlock()
+	if err != nil {
+		a.setStatus("⚠ " + err.Error() + " — run 'apo config'")
+		return
+	}
+	a.reconnect()
+	a.setStatus("Switched to profile " + name)
+}
+
+// switchProject rebinds everything to another project in the same
+// organization and remembers it for next time.
+func (a *App) switchProject(name string) {
+	if name == a.config.Project {
+		return
+	}
+	a.mu.Lock()
+	a.config.Project = name
+	err := a.config.RememberProject()
+	a.mu.Unlock()
+	a.reconnect()
+	if err != nil {
+		a.setStatus("⚠ Could not save last project: " + err.Error())
+		return
+	}
+	a.setStatus("Switched to project " + name)
+}
+
+// reconnect rebinds the client, agent and views to the current connection
+// and reloads all data. It runs on the main loop, which is the only writer
+// of a.config, so reading the config here needs no lock.
+func (a *App) reconnect() {
+	client := a.newClient(a.config)
+	ag := agent.New(client)
+	detailCfg := details.DetailConfig{
+		Organization:    a.config.Organization,
+		OrganizationURL: a.config.OrganizationURL(),
+		Project:         a.config.Project,
+	}
+
+	a.mu.Lock()
+	a.copilot.SetAgent(ag)
+	a.workItemDetail.SetConfig(detailCfg)
+	a.prDetail.SetConfig(detailCfg)
+	if a.isDetailView() {
+		a.currentView = views.ViewDashboard
+		a.tabBar.SetActiveByID("dashboard")
+	}
+	a.client = client
+	a.agent = ag
+	a.mu.Unlock()
+
+	a.reload()
+}
+
+// reload discards all data and loads it again. Loads still in flight are
+// canceled and their results discarded.
+func (a *App) reload() {
+	if a.refreshCancel != nil {
+		a.refreshCancel()
+	}
+	if a.pollCancel != nil {
+		a.pollCancel()
+	}
+
+	a.mu.Lock()
+	a.snapshots = a.snapshotStore()
+	a.workItems = nil
+	a.builds = nil
+	a.pipelineList = nil
+	a.repoList = nil
+	a.prList = nil
+	a.lastRefresh = time.Time{}
+	a.staleSince = time.Time{}
+	a.offline = false
+	a.loaded = make(map[section]bool)
+	a.marks = make(map[section]map[string]mark)
+	a.boards.ClearQuery()
+	a.boards.SetWorkItems(nil)
+	a.pipelines.SetPipelines(nil)
+	a.repos.SetRepositories(nil)
+	a.prs.SetPullRequests(nil)
+	a.dashboard.SetData(nil, nil, nil)
+	for _, sec := range allSections {
+		a.applyHighlights(sec)
+	}
+	a.statusBar.SetStaleSince(time.Time{}, false)
+	a.statusBar.SetLastRefresh(time.Time{})
+	a.mu.Unlock()
+
+	a.loadSnapshot()
+	a.startRefresh(false)
+	a.startPolling()
+}
@@ -113,6 +113,39 @@ func (t *Terminal) DisableRawMode() error {
 	return cmd.Run()
 }
 
+// ReadPassword reads a line from stdin without echoing it.
+func ReadPassword() (string, error) {
+	off := exec.Command("stty", "-echo")
+	off.Stdin = os.Stdin
+	if err := off.Run(); err == nil {
+		defer func() {
+			on := exec.Command("stty", "echo")
+			on.Stdin = os.Stdin
+			on.Run()
+		}()
+	}
+
+	// Read a byte at a time so no input past the line is consumed.
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			if len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
 // ReadKey reads a single key.
 func (t *Terminal) ReadKey() (Key, error) {
 	buf := make([]byte, 3)
@@ -165,14 +198,21 @@ func Style(text string, codes ...string) string {
 
 // Pad pads a string to width.
 func Pad(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
 	if len(s) >= width {
 		return s[:width]
 	}
 	return s + strings.Repeat(" ", width-len(s))
 }
 
-// Truncate truncates with ellipsis.
+// Truncate truncates with ellipsis. It returns "" when max is not
+// positive, so widths computed for a narrow terminal are safe to pass.
 func Truncate(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
 	if len(s) <= max {
 		return s
 	}
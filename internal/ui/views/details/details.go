@@ -3,26 +3,57 @@ package details
 
 import (
 	"fmt"
+	"html"
+	neturl "net/url"
 	"strings"
 	"time"
 
 	"github.com/user/apo/internal/agent"
 	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/components"
 	"github.com/user/apo/internal/ui/terminal"
 	"github.com/user/apo/internal/ui/views"
 )
 
 // DetailConfig holds configuration for detail views.
 type DetailConfig struct {
-	Organization string
-	Project      string
+	Organization    string
+	OrganizationURL string // base of web links, e.g. https://dev.azure.com/contoso
+	Project         string
 }
 
-// WorkItemDetailView shows work item details.
+// projectURL returns the web URL of a project.
+func (c DetailConfig) projectURL(project string) string {
+	return c.OrganizationURL + "/" + neturl.PathEscape(project)
+}
+
+// WorkItemDetailView shows work item details, with the description and
+// discussion, or the history, below them in a scrollable pane.
 type WorkItemDetailView struct {
 	views.BaseView
-	workItem *domain.WorkItem
-	config   DetailConfig
+	workItem  *domain.WorkItem
+	config    DetailConfig
+	onEdit    func(field string)
+	onComment func()
+	onHistory func()
+
+	comments      []domain.Comment
+	commentsState components.LoadState
+	scroll        int // first line of the pane shown
+	paneHeight    int // lines the pane showed at the last render
+
+	showHistory  bool
+	history      []domain.WorkItemUpdate // oldest first, as the API returns them
+	historyState components.LoadState
+	revision     int // revision selected, counting from the newest
+}
+
+// editKeys are the shortcuts that edit a field directly; 'e' asks which
+// field to edit.
+var editKeys = map[rune]string{
+	's': "System.State",
+	'a': "System.AssignedTo",
+	't': "System.Title",
 }
 
 // NewWorkItemDetailView creates a work item detail view.
@@ -33,11 +64,56 @@ func NewWorkItemDetailView(term *terminal.Terminal, cfg DetailConfig) *WorkItemD
 	}
 }
 
-// SetWorkItem sets the work item.
+// SetConfig sets the organization and project used for web links.
+func (v *WorkItemDetailView) SetConfig(cfg DetailConfig) { v.config = cfg }
+
+// SetWorkItem sets the work item. Comments and scrolling are kept when it
+// is a newer revision of the one shown.
 func (v *WorkItemDetailView) SetWorkItem(item *domain.WorkItem) {
+	if v.workItem == nil || item == nil || v.workItem.ID != item.ID {
+		v.comments = nil
+		v.commentsState = components.LoadState{}
+		v.scroll = 0
+		v.showHistory = false
+	}
+	if v.workItem == nil || item == nil || v.workItem.ID != item.ID || v.workItem.Rev != item.Rev {
+		v.history = nil
+		v.historyState = components.LoadState{}
+		v.revision = 0
+	}
 	v.workItem = item
 }
 
+// SetComments sets the discussion of work item id, ignoring it if another
+// work item is shown by now.
+func (v *WorkItemDetailView) SetComments(id int, comments []domain.Comment, state components.LoadState) {
+	if v.workItem == nil || v.workItem.ID != id {
+		return
+	}
+	v.comments = comments
+	v.commentsState = state
+}
+
+// Comments returns the discussion shown.
+func (v *WorkItemDetailView) Comments() []domain.Comment { return v.comments }
+
+// ScrollToBottom scrolls the pane to the latest comment.
+func (v *WorkItemDetailView) ScrollToBottom() { v.scroll = 1 << 30 }
+
+// WorkItem returns the work item shown.
+func (v *WorkItemDetailView) WorkItem() *domain.WorkItem { return v.workItem }
+
+// OnEdit sets the callback for editing a field, given by reference name,
+// or "" to choose one.
+func (v *WorkItemDetailView) OnEdit(fn func(field string)) { v.onEdit = fn }
+
+// OnComment sets the callback for adding a comment.
+func (v *WorkItemDetailView) OnComment(fn func()) { v.onComment = fn }
+
+// OnHistory sets the callback for loading the history, called when the
+// history tab is opened and has not been loaded for this revision.
+func (v *WorkItemDetailView) OnHistory(fn func()) { v.onHistory = fn }
+
 // Render renders the detail view.
 func (v *WorkItemDetailView) Render(startRow, width, height int) {
 	if v.workItem == nil {
@@ -58,6 +134,10 @@ func (v *WorkItemDetailView) Render(startRow, width, height int) {
 	fmt.Print(terminal.Style("State: ", terminal.Dim))
 	fmt.Print(terminal.Style(stateIcon+" "+item.State(), terminal.Bold, terminal.FgYellow))
 
+	term.MoveTo(startRow+4, width/2)
+	fmt.Print(terminal.Style("Priority: ", terminal.Dim))
+	fmt.Print(orDash(item.Priority()))
+
 	row := startRow + 6
 	term.MoveTo(row, 2)
 	fmt.Print(terminal.Style("Assigned To: ", terminal.Dim))
@@ -67,35 +147,172 @@ func (v *WorkItemDetailView) Render(startRow, width, height int) {
 	fmt.Print(terminal.Style("Created: ", terminal.Dim))
 	fmt.Print(formatDate(item.GetField("System.CreatedDate")))
 
-	descRow := startRow + 9
-	term.MoveTo(descRow, 2)
-	fmt.Print(terminal.Style("─── Description ", terminal.Dim))
-	fmt.Print(terminal.Style(strings.Repeat("─", width-20), terminal.Dim))
+	term.MoveTo(row+1, 2)
+	fmt.Print(terminal.Style("Iteration: ", terminal.Dim))
+	fmt.Print(terminal.Truncate(orDash(item.IterationPath()), width/2-14))
 
-	desc := item.GetField("System.Description")
-	if desc != "" {
-		desc = stripHTML(desc)
-		lines := wrapText(desc, width-6)
-		for i, line := range lines {
-			if descRow+1+i >= startRow+height-2 {
-				break
-			}
-			term.MoveTo(descRow+1+i, 4)
-			fmt.Print(line)
+	term.MoveTo(row+1, width/2)
+	fmt.Print(terminal.Style("Area: ", terminal.Dim))
+	fmt.Print(terminal.Truncate(orDash(item.AreaPath()), width/2-8))
+
+	v.renderTabs(startRow+8, width)
+	paneRow := startRow + 9
+	v.paneHeight = max(height-11, 1)
+	if v.showHistory {
+		v.renderHistory(paneRow, width)
+		v.renderURL(startRow+height-2, width)
+		return
+	}
+
+	lines := v.paneLines(width)
+	v.scroll = max(min(v.scroll, len(lines)-v.paneHeight), 0)
+	for i := 0; i < v.paneHeight && v.scroll+i < len(lines); i++ {
+		term.MoveTo(paneRow+i, 2)
+		fmt.Print(lines[v.scroll+i])
+	}
+
+	v.renderURL(startRow+height-2, width)
+	if v.scroll+v.paneHeight < len(lines) {
+		term.MoveTo(startRow+height-2, width-8)
+		fmt.Print(terminal.Style("↓ more", terminal.Dim))
+	}
+}
+
+// renderTabs draws the names of the pane's tabs, the shown one
+// highlighted.
+func (v *WorkItemDetailView) renderTabs(row, width int) {
+	v.Term().MoveTo(row, 2)
+	for i, name := range []string{"Details", "History"} {
+		if i > 0 {
+			fmt.Print(terminal.Style(" │ ", terminal.Dim))
+		}
+		if (i == 1) == v.showHistory {
+			fmt.Print(terminal.Style(" "+name+" ", terminal.Reverse, terminal.Bold))
+		} else {
+			fmt.Print(terminal.Style(" "+name+" ", terminal.Dim))
+		}
+	}
+}
+
+// renderURL draws the work item's web link.
+func (v *WorkItemDetailView) renderURL(row, width int) {
+	project := v.config.Project
+	if p := v.workItem.TeamProject(); p != "" {
+		project = p
+	}
+	url := fmt.Sprintf("%s/_workitems/edit/%d", v.config.projectURL(project), v.workItem.ID)
+	v.Term().MoveTo(row, 2)
+	fmt.Print(terminal.Style("URL: "+terminal.Truncate(url, width-20), terminal.Dim))
+}
+
+// paneLines returns the description and discussion, styled and wrapped to
+// width.
+func (v *WorkItemDetailView) paneLines(width int) []string {
+	section := func(title string) string {
+		return terminal.Style("─── "+title+" "+strings.Repeat("─", max(width-8-len([]rune(title)), 0)), terminal.Dim)
+	}
+
+	lines := []string{section("Description")}
+	if desc := stripHTML(v.workItem.GetField("System.Description")); desc != "" {
+		for _, line := range wrapText(desc, width-6) {
+			lines = append(lines, "  "+line)
 		}
 	} else {
-		term.MoveTo(descRow+1, 4)
-		fmt.Print(terminal.Style("No description.", terminal.Dim))
+		lines = append(lines, "  "+terminal.Style("No description.", terminal.Dim))
 	}
+	lines = append(lines, "")
 
-	term.MoveTo(startRow+height-2, 2)
-	url := fmt.Sprintf("https://dev.azure.com/%s/%s/_workitems/edit/%d",
-		v.config.Organization, v.config.Project, item.ID)
-	fmt.Print(terminal.Style("URL: "+terminal.Truncate(url, width-10), terminal.Dim))
+	title := "Discussion"
+	if len(v.comments) > 0 {
+		title = fmt.Sprintf("Discussion (%d)", len(v.comments))
+	}
+	lines = append(lines, section(title))
+	switch {
+	case v.commentsState.Loading:
+		lines = append(lines, "  "+terminal.Style("⏳ Loading comments...", terminal.Dim))
+	case v.commentsState.Error != "":
+		lines = append(lines, "  "+terminal.Style("⚠ "+v.commentsState.Error, terminal.FgRed))
+	case len(v.comments) == 0:
+		lines = append(lines, "  "+terminal.Style("No comments yet. Press c to add one.", terminal.Dim))
+	}
+	for i, c := range v.comments {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "  "+terminal.Style(c.CreatedBy.ShortName(), terminal.Bold, terminal.FgCyan)+
+			terminal.Style(" · "+c.CreatedDate.Local().Format("Jan 2, 2006 15:04"), terminal.Dim))
+		for _, line := range wrapText(stripHTML(c.Text), width-8) {
+			lines = append(lines, "    "+line)
+		}
+	}
+	return lines
 }
 
 // HandleKey handles input.
-func (v *WorkItemDetailView) HandleKey(key terminal.Key) bool { return false }
+func (v *WorkItemDetailView) HandleKey(key terminal.Key) bool {
+	if v.workItem == nil {
+		return false
+	}
+	switch key.Type {
+	case terminal.KeyUp:
+		v.move(-1)
+		return true
+	case terminal.KeyDown:
+		v.move(1)
+		return true
+	case terminal.KeyTab:
+		v.toggleHistory()
+		return true
+	case terminal.KeyRune:
+		return v.handleRune(key.Rune)
+	}
+	return false
+}
+
+func (v *WorkItemDetailView) handleRune(r rune) bool {
+	switch r {
+	case 'k':
+		v.move(-1)
+		return true
+	case 'j':
+		v.move(1)
+		return true
+	case 'g':
+		v.move(-1 << 30)
+		return true
+	case 'G':
+		v.move(1 << 30)
+		return true
+	case 'h':
+		v.toggleHistory()
+		return true
+	case 'c':
+		if v.onComment != nil {
+			v.onComment()
+			return true
+		}
+	case 'e':
+		if v.onEdit != nil {
+			v.onEdit("")
+			return true
+		}
+	}
+	if field, ok := editKeys[r]; ok && v.onEdit != nil {
+		v.onEdit(field)
+		return true
+	}
+	return false
+}
+
+// move scrolls the pane by n lines, or in the history tab selects the
+// revision n further down the list; Render keeps either in range.
+func (v *WorkItemDetailView) move(n int) {
+	if v.showHistory {
+		v.revision = max(min(v.revision+n, len(v.history)-1), 0)
+		return
+	}
+	v.scroll = max(v.scroll+n, 0)
+}
 
 // PRDetailView shows PR details.
 type PRDetailView struct {
@@ -112,6 +329,9 @@ func NewPRDetailView(term *terminal.Terminal, cfg DetailConfig) *PRDetailView {
 	}
 }
 
+// SetConfig sets the organization and project used for web links.
+func (v *PRDetailView) SetConfig(cfg DetailConfig) { v.config = cfg }
+
 // SetPullRequest sets the PR.
 func (v *PRDetailView) SetPullRequest(pr *domain.PullRequest) {
 	v.pr = pr
@@ -176,8 +396,12 @@ func (v *PRDetailView) Render(startRow, width, height int) {
 	}
 
 	term.MoveTo(startRow+height-2, 2)
-	url := fmt.Sprintf("https://dev.azure.com/%s/%s/_git/%s/pullrequest/%d",
-		v.config.Organization, v.config.Project, pr.Repository.Name, pr.PullRequestID)
+	project := v.config.Project
+	if p := pr.Repository.Project.Name; p != "" {
+		project = p
+	}
+	url := fmt.Sprintf("%s/_git/%s/pullrequest/%d",
+		v.config.projectURL(project), neturl.PathEscape(pr.Repository.Name), pr.PullRequestID)
 	fmt.Print(terminal.Style("URL: "+terminal.Truncate(url, width-10), terminal.Dim))
 }
 
@@ -198,6 +422,13 @@ func formatDate(dateStr string) string {
 	return t.Format("Jan 2, 2006")
 }
 
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
 func stripHTML(s string) string {
 	var result strings.Builder
 	inTag := false
@@ -210,10 +441,7 @@ func stripHTML(s string) string {
 			result.WriteRune(r)
 		}
 	}
-	text := result.String()
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	return strings.TrimSpace(text)
+	return strings.TrimSpace(html.UnescapeString(result.String()))
 }
 
 func wrapText(text string, width int) []string {
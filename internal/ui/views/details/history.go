@@ -0,0 +1,176 @@
+package details
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/user/apo/internal/domain"
+	"github.com/user/apo/internal/ui/components"
+	"github.com/user/apo/internal/ui/terminal"
+)
+
+// relationNames are the names the web UI gives the usual link types.
+var relationNames = map[string]string{
+	"System.LinkTypes.Hierarchy-Reverse": "Parent",
+	"System.LinkTypes.Hierarchy-Forward": "Child",
+	"System.LinkTypes.Related":           "Related",
+	"System.LinkTypes.Duplicate-Forward": "Duplicate",
+	"System.LinkTypes.Duplicate-Reverse": "Duplicate of",
+	"ArtifactLink":                       "Artifact",
+	"Hyperlink":                          "Hyperlink",
+}
+
+// SetHistory sets the revisions of work item id, oldest first as the API
+// returns them, ignoring them if another work item is shown by now.
+func (v *WorkItemDetailView) SetHistory(id int, updates []domain.WorkItemUpdate, state components.LoadState) {
+	if v.workItem == nil || v.workItem.ID != id {
+		return
+	}
+	v.history = updates
+	v.historyState = state
+	v.revision = max(min(v.revision, len(v.history)-1), 0)
+}
+
+// History returns the revisions shown, oldest first.
+func (v *WorkItemDetailView) History() []domain.WorkItemUpdate { return v.history }
+
+// HistoryShown reports whether the history tab is shown.
+func (v *WorkItemDetailView) HistoryShown() bool { return v.showHistory }
+
+// toggleHistory switches between the details and history tabs, asking for
+// the history the first time it is shown.
+func (v *WorkItemDetailView) toggleHistory() {
+	v.showHistory = !v.showHistory
+	if v.showHistory && v.history == nil && !v.historyState.Loading && v.onHistory != nil {
+		v.onHistory()
+	}
+}
+
+// renderHistory draws the revision list on the left and the selected
+// revision's changes on the right.
+func (v *WorkItemDetailView) renderHistory(row, width int) {
+	term := v.Term()
+	switch {
+	case v.historyState.Loading && len(v.history) == 0:
+		term.MoveTo(row, 4)
+		fmt.Print(terminal.Style("⏳ Loading history...", terminal.Dim))
+		return
+	case v.historyState.Error != "":
+		term.MoveTo(row, 4)
+		fmt.Print(terminal.Style("⚠ "+v.historyState.Error, terminal.FgRed))
+		return
+	case len(v.history) == 0:
+		term.MoveTo(row, 4)
+		fmt.Print(terminal.Style("No history.", terminal.Dim))
+		return
+	}
+
+	listWidth := min(36, width/3)
+	first := max(v.revision-v.paneHeight+1, 0)
+	for i := 0; i < v.paneHeight && first+i < len(v.history); i++ {
+		u := v.revisionAt(first + i)
+		line := fmt.Sprintf(" Rev %-3d %s  %s", u.Rev, u.ChangedDate().Local().Format("Jan _2 15:04"), u.RevisedBy.ShortName())
+		line = components.PadRunes(line, listWidth-2)
+		term.MoveTo(row+i, 2)
+		if first+i == v.revision {
+			fmt.Print(terminal.Style(line, terminal.Reverse))
+		} else {
+			fmt.Print(line)
+		}
+	}
+	for i := 0; i < v.paneHeight; i++ {
+		term.MoveTo(row+i, listWidth+1)
+		fmt.Print(terminal.Style("│", terminal.Dim))
+	}
+
+	lines := revisionLines(v.revisionAt(v.revision), width-listWidth-5)
+	for i := 0; i < v.paneHeight && i < len(lines); i++ {
+		term.MoveTo(row+i, listWidth+3)
+		fmt.Print(lines[i])
+	}
+}
+
+// revisionAt returns the i-th revision in the list, which shows the newest
+// first.
+func (v *WorkItemDetailView) revisionAt(i int) domain.WorkItemUpdate {
+	return v.history[len(v.history)-1-i]
+}
+
+// revisionLines returns what an update changed, one "Field: old → new"
+// entry per field followed by the links added and removed, styled and
+// wrapped to width.
+func revisionLines(u domain.WorkItemUpdate, width int) []string {
+	lines := []string{
+		terminal.Style(fmt.Sprintf("Rev %d", u.Rev), terminal.Bold, terminal.FgCyan) +
+			terminal.Style(" by "+u.RevisedBy.ShortName()+" · "+u.ChangedDate().Local().Format("Jan 2, 2006 15:04"), terminal.Dim),
+		"",
+	}
+	for _, name := range u.ChangedFields() {
+		change := u.Fields[name]
+		label := fieldLabel(name)
+		if name == "System.History" {
+			lines = append(lines, terminal.Style(label+":", terminal.Dim))
+			for _, line := range wrapText(stripHTML(change.New()), width-2) {
+				lines = append(lines, "  "+line)
+			}
+			continue
+		}
+		before, after := orDash(stripHTML(change.Old())), orDash(stripHTML(change.New()))
+		text := before + " → " + after
+		if change.OldValue == nil {
+			text = after
+		}
+		for i, line := range wrapText(text, width-len(label)-2) {
+			if i == 0 {
+				lines = append(lines, terminal.Style(label+": ", terminal.Dim)+line)
+			} else {
+				lines = append(lines, strings.Repeat(" ", len(label)+2)+line)
+			}
+		}
+	}
+	if r := u.Relations; r != nil {
+		for _, rel := range r.Added {
+			lines = append(lines, terminal.Style("+ "+relationLabel(rel), terminal.FgGreen))
+		}
+		for _, rel := range r.Removed {
+			lines = append(lines, terminal.Style("- "+relationLabel(rel), terminal.FgRed))
+		}
+	}
+	if len(lines) == 2 {
+		lines = append(lines, terminal.Style("No field changes in this revision.", terminal.Dim))
+	}
+	return lines
+}
+
+// fieldLabel turns a field reference name into a label, e.g.
+// "Microsoft.VSTS.Common.Priority" into "Priority" and
+// "System.AssignedTo" into "Assigned To".
+func fieldLabel(name string) string {
+	if name == "System.History" {
+		return "Comment"
+	}
+	name = name[strings.LastIndex(name, ".")+1:]
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// relationLabel describes a link, e.g. "Parent #1000".
+func relationLabel(rel domain.WorkItemRelation) string {
+	name, ok := relationNames[rel.Rel]
+	if !ok {
+		name = rel.Rel
+	}
+	if strings.Contains(rel.URL, "/workItems/") || strings.Contains(rel.URL, "/workitems/") {
+		return name + " #" + path.Base(rel.URL)
+	}
+	return name + " " + rel.URL
+}
@@ -0,0 +1,65 @@
+package details
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/user/apo/internal/domain"
+)
+
+func TestFieldLabel(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"System.Title", "Title"},
+		{"System.AssignedTo", "Assigned To"},
+		{"System.History", "Comment"},
+		{"System.IterationPath", "Iteration Path"},
+		{"Microsoft.VSTS.Common.Priority", "Priority"},
+		{"Microsoft.VSTS.Scheduling.StoryPoints", "Story Points"},
+		{"Microsoft.VSTS.Common.ValueArea", "Value Area"},
+		{"Custom.ABCReference", "ABC Reference"},
+		{"Custom.PRNumber", "PR Number"},
+		{"Custom.TShirt", "T Shirt"},
+		{"Custom.lowercase", "lowercase"},
+		{"NoNamespace", "No Namespace"},
+	}
+	for _, tt := range tests {
+		if got := fieldLabel(tt.name); got != tt.want {
+			t.Errorf("fieldLabel(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRelationLabel(t *testing.T) {
+	tests := []struct {
+		rel  domain.WorkItemRelation
+		want string
+	}{
+		{domain.WorkItemRelation{Rel: "System.LinkTypes.Hierarchy-Reverse", URL: "https://dev.azure.com/org/_apis/wit/workItems/1000"}, "Parent #1000"},
+		{domain.WorkItemRelation{Rel: "System.LinkTypes.Related", URL: "https://dev.azure.com/org/_apis/wit/workitems/12"}, "Related #12"},
+		{domain.WorkItemRelation{Rel: "Hyperlink", URL: "https://example.com/spec"}, "Hyperlink https://example.com/spec"},
+		{domain.WorkItemRelation{Rel: "Custom.LinkType", URL: "https://dev.azure.com/org/_apis/wit/workItems/5"}, "Custom.LinkType #5"},
+	}
+	for _, tt := range tests {
+		if got := relationLabel(tt.rel); got != tt.want {
+			t.Errorf("relationLabel(%+v) = %q, want %q", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestRevisionLinesNeverPanic(t *testing.T) {
+	u := domain.WorkItemUpdate{
+		Rev: 2,
+		Fields: map[string]domain.FieldChange{
+			"System.State":   {OldValue: "New", NewValue: "Active"},
+			"System.History": {NewValue: "<div>Looks good</div>"},
+		},
+	}
+	for _, width := range []int{-10, 0, 1, 5, 80} {
+		lines := revisionLines(u, width)
+		if !strings.Contains(strings.Join(lines, "\n"), "State") {
+			t.Errorf("width %d: lines %q do not mention the State change", width, lines)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package views
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/user/apo/internal/agent"
@@ -11,19 +13,71 @@ import (
 	"github.com/user/apo/internal/ui/terminal"
 )
 
+// DashboardSection identifies a panel on the dashboard.
+type DashboardSection int
+
+const (
+	DashboardWorkItems DashboardSection = iota
+	DashboardBuilds
+	DashboardPullRequests
+)
+
 // DashboardView provides an overview.
 type DashboardView struct {
 	BaseView
 	workItems []domain.WorkItem
 	builds    []domain.Build
 	prs       []domain.PullRequest
+	states    map[DashboardSection]components.LoadState
+	marks     map[DashboardSection]map[string]components.Highlight
+	projects  bool
+}
+
+// projectColumnWidth is the width of the project column shown when rows
+// come from several projects.
+const projectColumnWidth = 12
+
+// minTitleWidth is the narrowest a dashboard title may get before the
+// project column is dropped to make room for it.
+const minTitleWidth = 10
+
+// projectColumn formats a project name as a fixed-width column.
+func projectColumn(name string) string {
+	return terminal.Pad(terminal.Truncate(name, projectColumnWidth), projectColumnWidth) + " "
+}
+
+// title truncates a dashboard row's text to width, after the project
+// column when rows come from several projects and there is room for both.
+func (v *DashboardView) title(project, text string, width int) string {
+	if v.projects && width-projectColumnWidth-1 >= minTitleWidth {
+		return projectColumn(project) + terminal.Truncate(text, width-projectColumnWidth-1)
+	}
+	return terminal.Truncate(text, max(width, 0))
 }
 
 // NewDashboardView creates a dashboard view.
 func NewDashboardView(term *terminal.Terminal) *DashboardView {
-	return &DashboardView{BaseView: NewBaseView(term, ViewDashboard, "Dashboard")}
+	return &DashboardView{
+		BaseView: NewBaseView(term, ViewDashboard, "Dashboard"),
+		states:   make(map[DashboardSection]components.LoadState),
+		marks:    make(map[DashboardSection]map[string]components.Highlight),
+	}
+}
+
+// SetLoadState sets the load state shown in a panel's header.
+func (v *DashboardView) SetLoadState(section DashboardSection, state components.LoadState) {
+	v.states[section] = state
 }
 
+// SetHighlights marks changed rows in a panel, keyed by work item, build or
+// pull request ID.
+func (v *DashboardView) SetHighlights(section DashboardSection, highlights map[string]components.Highlight) {
+	v.marks[section] = highlights
+}
+
+// SetShowProject sets whether rows start with their project's name.
+func (v *DashboardView) SetShowProject(show bool) { v.projects = show }
+
 // SetData sets dashboard data.
 func (v *DashboardView) SetData(items []domain.WorkItem, builds []domain.Build, prs []domain.PullRequest) {
 	v.workItems = items
@@ -39,6 +93,7 @@ func (v *DashboardView) Render(startRow, width, height int) {
 	// Work Items
 	v.term.MoveTo(startRow, 2)
 	fmt.Print(terminal.Style("📋 My Work Items", terminal.Bold, terminal.FgYellow))
+	v.states[DashboardWorkItems].Render()
 	row := startRow + 1
 	for i, item := range v.workItems {
 		if i >= halfHeight-1 {
@@ -46,7 +101,9 @@ func (v *DashboardView) Render(startRow, width, height int) {
 		}
 		v.term.MoveTo(row, 2)
 		icon := agent.GetWorkItemIcon(item.Type())
-		fmt.Printf("%s #%d %s", icon, item.ID, terminal.Truncate(item.Title(), colWidth-15))
+		title := v.title(item.TeamProject(), item.Title(), colWidth-15)
+		fmt.Printf("%s #%d %s", icon, item.ID, title)
+		v.marks[DashboardWorkItems][fmt.Sprintf("%d", item.ID)].Render()
 		row++
 	}
 	if len(v.workItems) == 0 {
@@ -57,6 +114,7 @@ func (v *DashboardView) Render(startRow, width, height int) {
 	// Builds
 	v.term.MoveTo(startRow, colWidth+3)
 	fmt.Print(terminal.Style("🔧 Recent Builds", terminal.Bold, terminal.FgYellow))
+	v.states[DashboardBuilds].Render()
 	row = startRow + 1
 	for i, b := range v.builds {
 		if i >= halfHeight-1 {
@@ -64,7 +122,9 @@ func (v *DashboardView) Render(startRow, width, height int) {
 		}
 		v.term.MoveTo(row, colWidth+3)
 		icon := agent.GetBuildIcon(b.Result)
-		fmt.Printf("%s #%s %s", icon, b.BuildNumber, terminal.Truncate(b.Definition.Name, colWidth-15))
+		name := v.title(b.Project.Name, b.Definition.Name, colWidth-15)
+		fmt.Printf("%s #%s %s", icon, b.BuildNumber, name)
+		v.marks[DashboardBuilds][fmt.Sprintf("%d", b.ID)].Render()
 		row++
 	}
 	if len(v.builds) == 0 {
@@ -75,6 +135,7 @@ func (v *DashboardView) Render(startRow, width, height int) {
 	// PRs
 	v.term.MoveTo(startRow+halfHeight+1, 2)
 	fmt.Print(terminal.Style("🔀 Active Pull Requests", terminal.Bold, terminal.FgYellow))
+	v.states[DashboardPullRequests].Render()
 	row = startRow + halfHeight + 2
 	for i, pr := range v.prs {
 		if i >= halfHeight-2 {
@@ -85,7 +146,9 @@ func (v *DashboardView) Render(startRow, width, height int) {
 		if pr.IsDraft {
 			icon = "📝"
 		}
-		fmt.Printf("%s #%d %s", icon, pr.PullRequestID, terminal.Truncate(pr.Title, width-20))
+		title := v.title(pr.Repository.Project.Name, pr.Title, width-20)
+		fmt.Printf("%s #%d %s", icon, pr.PullRequestID, title)
+		v.marks[DashboardPullRequests][fmt.Sprintf("%d", pr.PullRequestID)].Render()
 		row++
 	}
 	if len(v.prs) == 0 {
@@ -97,23 +160,56 @@ func (v *DashboardView) Render(startRow, width, height int) {
 // HandleKey handles input.
 func (v *DashboardView) HandleKey(key terminal.Key) bool { return false }
 
-// BoardsView displays work items.
+// BoardsView displays work items: the user's own, or the results of a
+// saved query.
 type BoardsView struct {
 	BaseView
 	list      *components.List
-	workItems []domain.WorkItem
+	workItems []domain.WorkItem // rows shown
+	mine      []domain.WorkItem
+	query     string // name of the saved query shown, if any
 	onSelect  func(*domain.WorkItem)
+	projects  bool
 }
 
+// boardsTitle is the list title while showing the user's own work items.
+const boardsTitle = "📋 Work Items"
+
 // NewBoardsView creates a boards view.
 func NewBoardsView(term *terminal.Terminal) *BoardsView {
 	v := &BoardsView{BaseView: NewBaseView(term, ViewBoards, "Boards")}
-	v.list = components.NewList(term, "📋 Work Items")
+	v.list = components.NewList(term, boardsTitle)
 	return v
 }
 
-// SetWorkItems sets work items.
+// SetWorkItems sets the user's work items. They are shown unless a saved
+// query's results are.
 func (v *BoardsView) SetWorkItems(items []domain.WorkItem) {
+	v.mine = items
+	if v.query == "" {
+		v.show(items)
+	}
+}
+
+// SetQueryResults shows the results of the named saved query in place of
+// the user's work items.
+func (v *BoardsView) SetQueryResults(name string, items []domain.WorkItem) {
+	v.query = name
+	v.list.SetTitle("🔎 " + name)
+	v.show(items)
+}
+
+// ClearQuery goes back to showing the user's work items.
+func (v *BoardsView) ClearQuery() {
+	v.query = ""
+	v.list.SetTitle(boardsTitle)
+	v.show(v.mine)
+}
+
+// Query returns the name of the saved query shown, or "".
+func (v *BoardsView) Query() string { return v.query }
+
+func (v *BoardsView) show(items []domain.WorkItem) {
 	v.workItems = items
 	listItems := make([]components.ListItem, len(items))
 	for i, item := range items {
@@ -123,10 +219,27 @@ func (v *BoardsView) SetWorkItems(items []domain.WorkItem) {
 			Label: fmt.Sprintf("#%d %s [%s]", item.ID, item.Title(), item.State()),
 			Data:  item,
 		}
+		if v.projects {
+			listItems[i].Label = projectColumn(item.TeamProject()) + listItems[i].Label
+		}
 	}
 	v.list.SetItems(listItems)
 }
 
+// SetShowProject sets whether rows start with their project's name.
+func (v *BoardsView) SetShowProject(show bool) {
+	v.projects = show
+	v.show(v.workItems)
+}
+
+// SetLoadState sets the work items load state.
+func (v *BoardsView) SetLoadState(state components.LoadState) { v.list.SetLoadState(state) }
+
+// SetHighlights marks changed rows, keyed by ID.
+func (v *BoardsView) SetHighlights(highlights map[string]components.Highlight) {
+	v.list.SetHighlights(highlights)
+}
+
 // OnSelectItem sets the select callback.
 func (v *BoardsView) OnSelectItem(fn func(*domain.WorkItem)) { v.onSelect = fn }
 
@@ -231,6 +344,14 @@ func (v *PipelinesView) SetPipelines(items []domain.Pipeline) {
 	v.list.SetItems(listItems)
 }
 
+// SetLoadState sets the pipelines load state.
+func (v *PipelinesView) SetLoadState(state components.LoadState) { v.list.SetLoadState(state) }
+
+// SetHighlights marks changed rows, keyed by ID.
+func (v *PipelinesView) SetHighlights(highlights map[string]components.Highlight) {
+	v.list.SetHighlights(highlights)
+}
+
 // Render renders the view.
 func (v *PipelinesView) Render(startRow, width, height int) {
 	v.list.Render(startRow, 2, width, height)
@@ -316,6 +437,14 @@ func (v *ReposView) SetRepositories(items []domain.Repository) {
 	v.list.SetItems(listItems)
 }
 
+// SetLoadState sets the repositories load state.
+func (v *ReposView) SetLoadState(state components.LoadState) { v.list.SetLoadState(state) }
+
+// SetHighlights marks changed rows, keyed by ID.
+func (v *ReposView) SetHighlights(highlights map[string]components.Highlight) {
+	v.list.SetHighlights(highlights)
+}
+
 // Render renders the view.
 func (v *ReposView) Render(startRow, width, height int) {
 	v.list.Render(startRow, 2, width, height)
@@ -379,6 +508,7 @@ type PullRequestsView struct {
 	list     *components.List
 	prs      []domain.PullRequest
 	onSelect func(*domain.PullRequest)
+	projects bool
 }
 
 // NewPullRequestsView creates a PRs view.
@@ -402,10 +532,27 @@ func (v *PullRequestsView) SetPullRequests(items []domain.PullRequest) {
 			Icon:  icon,
 			Label: fmt.Sprintf("#%d %s (%s→%s)", pr.PullRequestID, pr.Title, pr.SourceBranch(), pr.TargetBranch()),
 		}
+		if v.projects {
+			listItems[i].Label = projectColumn(pr.Repository.Project.Name) + listItems[i].Label
+		}
 	}
 	v.list.SetItems(listItems)
 }
 
+// SetShowProject sets whether rows start with their project's name.
+func (v *PullRequestsView) SetShowProject(show bool) {
+	v.projects = show
+	v.SetPullRequests(v.prs)
+}
+
+// SetLoadState sets the pull requests load state.
+func (v *PullRequestsView) SetLoadState(state components.LoadState) { v.list.SetLoadState(state) }
+
+// SetHighlights marks changed rows, keyed by ID.
+func (v *PullRequestsView) SetHighlights(highlights map[string]components.Highlight) {
+	v.list.SetHighlights(highlights)
+}
+
 // OnSelectItem sets select callback.
 func (v *PullRequestsView) OnSelectItem(fn func(*domain.PullRequest)) { v.onSelect = fn }
 
@@ -492,9 +639,16 @@ type CopilotMessage struct {
 // CopilotView provides NL interaction.
 type CopilotView struct {
 	BaseView
-	input   *components.Input
-	agent   *agent.Agent
+	input    *components.Input
+	agent    *agent.Agent
+	onUpdate func()
+
+	mu      sync.Mutex
 	history []CopilotMessage
+	pending bool
+	parent  context.Context // what questions are asked under; see SetContext
+	ctx     context.Context
+	cancel  context.CancelFunc
 }
 
 // NewCopilotView creates a copilot view.
@@ -507,20 +661,65 @@ func NewCopilotView(term *terminal.Terminal, ag *agent.Agent) *CopilotView {
 	return v
 }
 
+// SetContext sets the context questions are asked under, so that
+// canceling it, e.g. by quitting, cancels any still in flight.
+func (v *CopilotView) SetContext(ctx context.Context) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.parent = ctx
+}
+
+// SetAgent replaces the agent answering questions, e.g. after the
+// connection changed. Questions in flight with the old one are canceled.
+func (v *CopilotView) SetAgent(ag *agent.Agent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.agent = ag
+	v.restart()
+}
+
+// restart cancels the question in flight, if any, and starts a new
+// context for the next ones. It must be called with v.mu held.
+func (v *CopilotView) restart() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+	v.pending = false
+	parent := v.parent
+	if parent == nil {
+		parent = context.Background()
+	}
+	v.ctx, v.cancel = context.WithCancel(parent)
+}
+
+// OnUpdate sets a callback invoked when an answer arrives in the background.
+func (v *CopilotView) OnUpdate(fn func()) { v.onUpdate = fn }
+
 // OnEnter activates input.
 func (v *CopilotView) OnEnter() {
+	v.mu.Lock()
+	v.restart()
+	v.mu.Unlock()
 	v.input.Activate()
 	v.term.ShowCursor()
 }
 
-// OnExit deactivates input.
+// OnExit deactivates input and cancels any query still in flight.
 func (v *CopilotView) OnExit() {
+	v.mu.Lock()
+	if v.cancel != nil {
+		v.cancel()
+	}
+	v.mu.Unlock()
 	v.input.Deactivate()
 	v.term.HideCursor()
 }
 
 // Render renders the view.
 func (v *CopilotView) Render(startRow, width, height int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
 	v.term.MoveTo(startRow, 2)
 	fmt.Print(terminal.Style("🤖 Copilot - Ask me about Azure DevOps", terminal.Bold, terminal.FgCyan))
 
@@ -551,6 +750,11 @@ func (v *CopilotView) Render(startRow, width, height int) {
 		}
 	}
 
+	if v.pending {
+		v.term.MoveTo(startRow+height-3, 2)
+		fmt.Print(terminal.Style("⏳ Thinking...", terminal.FgYellow))
+	}
+
 	v.input.Render(startRow+height-2, 2, width-4)
 }
 
@@ -576,10 +780,36 @@ func (v *CopilotView) submit() {
 		return
 	}
 
+	v.mu.Lock()
+	if v.pending {
+		v.mu.Unlock()
+		return
+	}
+	if v.ctx == nil {
+		v.restart()
+	}
 	v.history = append(v.history, CopilotMessage{IsUser: true, Content: query, Time: time.Now()})
+	v.pending = true
+	ctx := v.ctx
+	ag := v.agent
+	v.mu.Unlock()
 	v.input.Clear()
 
-	result := v.agent.Ask(query)
+	go v.answer(ctx, ag, query)
+}
+
+// answer asks ag and shows the result, unless the question was canceled
+// meanwhile: its answer would belong to a connection no longer shown, and a
+// newer question may be pending.
+func (v *CopilotView) answer(ctx context.Context, ag *agent.Agent, query string) {
+	result := ag.Ask(ctx, query)
+
+	v.mu.Lock()
+	if ctx.Err() != nil {
+		v.mu.Unlock()
+		return
+	}
+	v.pending = false
 	v.history = append(v.history, CopilotMessage{Content: result.Message, Time: time.Now()})
 
 	for _, s := range result.Suggestions {
@@ -594,4 +824,9 @@ func (v *CopilotView) submit() {
 			}
 		}
 	}
+	v.mu.Unlock()
+
+	if v.onUpdate != nil {
+		v.onUpdate()
+	}
 }